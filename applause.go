@@ -7,6 +7,7 @@ import (
 	"reflect"
 
 	"github.com/noclaps/applause/internal/parser"
+	"github.com/noclaps/applause/internal/utils"
 )
 
 // The help string for the command. This will only contain a value if
@@ -19,12 +20,39 @@ var Help string = ""
 // string.
 var Usage string = ""
 
+// The parser used by the last call to [Parse].
+var parsed *parser.Parser
+
+// Implemented by types that only accept a fixed set of values. Fields of
+// these types will only accept the values returned by Values.
+type Enum = parser.Enum
+
+// Implemented by types that can parse themselves from a command line value.
+// Fields of these types, or of types implementing
+// [encoding.TextUnmarshaler], can be used as arguments and options.
+type Value = utils.Value
+
+// Implemented by args and command structs that need to run code before
+// their arguments are parsed, for instance to set defaults. BeforeParse is
+// called for the args struct and then for each command that is run.
+type BeforeParser = parser.BeforeParser
+
+// Implemented by args and command structs that need to check or change
+// their values after the arguments are parsed, for instance to validate
+// options that depend on each other. AfterParse is called for the args
+// struct and then for each command that was run, and the first error is
+// returned by [Parse].
+type AfterParser = parser.AfterParser
+
 /*
 The input is a pointer to the args struct. Each field in the args struct
 should have some tags:
 
-  - `type`: The type can be "arg", "option" or "command". If omitted, the
-    default is "arg". If any other type is provided, the field is ignored.
+  - `type`: The type can be "arg", "option", "command", "group" or
+    "command-path". If omitted, the default is "arg". If any other type is
+    provided, the field is ignored. Fields of type "command-path" should be
+    of type `string` or `[]string`, and are set to the names of the commands
+    that were selected below the struct, see [CommandPath].
 
   - `name`: The name of the argument, option or command. If omitted, the
     default is the field name in kebab-case. If you'd like to have an option
@@ -45,6 +73,94 @@ should have some tags:
     text. For instance, `name:"option" value:"val"` will be displayed as
     `--option <val>` in the help text.
 
+  - `persistent`: Only applicable when `type` is "option". If set to
+    "true", the option is also accepted by all subcommands, and is listed
+    under "GLOBAL OPTIONS" in their help text.
+
+  - `negatable`: Only applicable when `type` is "option" and the field type
+    is "bool". If set to "true", the option can also be called as
+    `--no-<name>` to set it to false. For instance, `name:"color"
+    negatable:"true"` can be called with `--color` or `--no-color`, and will
+    be displayed as `--[no-]color` in the help text.
+
+  - `implied`: Only applicable when `type` is "option" and the field type is
+    not "bool". Makes the option value optional, so it can be called as
+    `--option=val` or as `--option`, in which case the implied value is
+    used. For instance, `name:"color" value:"when" implied:"auto"` will be
+    displayed as `--color[=<when>]` in the help text, and `--color` will be
+    the same as `--color=auto`.
+
+  - `choices`: Only applicable when `type` is "arg", "option" or omitted. A
+    comma-separated list of allowed values, for instance
+    `choices:"red,green,blue"`. Any other value will return an error. If the
+    tag is omitted and the field type implements [Enum], its values are used
+    instead. The allowed values are displayed in the help text and used for
+    completions if `completion` is not set.
+
+  - `min`, `max`: Only applicable when `type` is "arg", "option" or omitted,
+    and the field type is numeric. The inclusive range of allowed values,
+    for instance `min:"1" max:"65535"`. Values outside the range will return
+    an error.
+
+  - `pattern`: Only applicable when `type` is "arg", "option" or omitted,
+    and the field type is "string". A regular expression that values must
+    match, for instance `pattern:"^[a-z0-9-]+$"`.
+
+  - `layout`: Only applicable when the field type is [time.Time]. The
+    layout used to parse the value, for instance `layout:"2006-01-02"`. If
+    omitted, values are parsed as RFC 3339.
+
+  - `stdin`: Only applicable when `type` is "arg" or omitted. Can be "line"
+    or "all". When the argument value is `-`, a single line is read from
+    stdin by default, or the whole of stdin if set to "all".
+
+  - `unit`: Only applicable when the field type is an integer type. If set
+    to "bytes", the value is parsed as a size with an optional suffix, such
+    as `512MiB` or `2G`. `K`, `M`, `G`, `T` and `P` are powers of 1000, and
+    `KiB`, `MiB`, `GiB`, `TiB` and `PiB` are powers of 1024.
+
+  - `env`: Only applicable when `type` is "option". The name of an
+    environment variable to read the value from if the option isn't set on
+    the command line.
+
+  - `aliases`: Only applicable when `type` is "option" or "command". A
+    comma-separated list of other names the option or command can be called
+    with, for instance `aliases:"rm,del"`. Aliases are displayed in the help
+    text.
+
+  - `hidden`: Only applicable when `type` is "option" or "command". If set
+    to "true", the option or command is left out of the help text, usage
+    and completions, but is still accepted.
+
+  - `deprecated`: The reason the argument, option or command is
+    deprecated, for instance `deprecated:"use --output instead"`. Deprecated
+    items are still accepted, but a warning is written when they're used,
+    see [WarningWriter]. They're marked as deprecated in the help text and
+    left out of the completions. Deprecated options and commands are also
+    left out of the usage, but deprecated arguments stay in it.
+
+  - `section`: Only applicable when `type` is "option". The heading the
+    option is displayed under in the help text, for instance
+    `section:"Network"`. Sections are displayed after the other options, in
+    the order they're first used.
+
+  - `group`, `exclusive`: Only applicable when `type` is "option". Options
+    with the same `group` cannot be used together if any of them is tagged
+    with `exclusive:"true"`, and are displayed as `[--json | --table]` in the
+    usage.
+
+  - `conflicts`: Only applicable when `type` is "option". A comma-separated
+    list of the names of options that cannot be used together with the
+    option on the command line, for instance `conflicts:"verbose"`.
+
+  - `requires`: Only applicable when `type` is "option". A comma-separated
+    list of the names of options that must be set when the option is set,
+    for instance `requires:"password"`.
+
+  - `default`: Only applicable when `type` is "command". If set to "true",
+    the command is run when the first argument isn't the name of a command,
+    with all of the arguments. Only one command can be the default.
+
   - `completion`: Only applicable when `type` is "arg", "option" or omitted.
     You can define a completion one of three ways:
 
@@ -61,21 +177,116 @@ should have some tags:
     for completions that need to be dynamic.
 
 All fields that you'd like to be parsed should be exported in the struct.
+
+Fields of struct types are commands, except for embedded structs and struct
+fields tagged with `embed:"true"` or `type:"group"`, whose arguments and
+options are added to the parent struct instead. The `prefix` tag on these
+fields adds a prefix to the names of their arguments and options, and the
+`section` tag sets the heading their options are displayed under. Short
+names aren't prefixed, and an error is returned if two options or two
+commands have the same name.
+
+Options can be passed in to change how the arguments are parsed, for
+instance [ConfigFile], [ResponseFiles] or [ExternalCommands].
 */
-func Parse(args any) error {
+func Parse(args any, options ...Option) error {
 	rv := reflect.ValueOf(args)
 	if rv.Kind() != reflect.Pointer || rv.IsNil() {
 		return fmt.Errorf("Input value should be a pointer to a struct, received: %v", rv.Kind().String())
 	}
 
+	settings := parser.Settings{}
+	for _, option := range options {
+		option(&settings)
+	}
+
+	arguments := os.Args[1:]
+	if settings.ResponseFiles {
+		expanded, err := utils.ExpandResponseFiles(arguments)
+		if err != nil {
+			return err
+		}
+		arguments = expanded
+	}
+
 	cmdName := path.Base(os.Args[0])
-	parser := parser.NewParser(cmdName, os.Args[1:], rv)
+	parser := parser.NewParser(cmdName, arguments, rv, &settings)
 	Help = parser.Help
 	Usage = parser.Usage
+	parsed = parser
 
 	if err := parser.Parse(); err != nil {
 		return err
 	}
+	if err := parser.CheckRelations(); err != nil {
+		return err
+	}
+	if err := parser.OpenFiles(); err != nil {
+		return err
+	}
+
+	return parser.AfterParse()
+}
+
+// Returned by [Parse] when an external command was run instead of the
+// program's own commands, see [ExternalCommands]. It's returned even if the
+// external command succeeded, and its ExitCode method returns the exit code
+// of the external command, or 128 plus the signal number if it was killed
+// by a signal.
+type ExternalCommandError = parser.ExternalCommandError
 
-	return nil
+// Wrapped by every [*ExternalCommandError], so errors.Is(err,
+// ErrExternalCommand) reports whether [Parse] ran an external command, even
+// if it succeeded. The program should then exit with [ExitCode] instead of
+// running its own code:
+//
+//	err := applause.Parse(&args, applause.ExternalCommands())
+//	if errors.Is(err, applause.ErrExternalCommand) {
+//		os.Exit(applause.ExitCode(err))
+//	}
+//	if err != nil {
+//		log.Fatalln(err)
+//	}
+var ErrExternalCommand = parser.ErrExternalCommand
+
+// Where the value of an argument or option came from, returned by [Source].
+type ValueSource = parser.Source
+
+const (
+	SourceDefault     = parser.SourceDefault     // the value in the struct before parsing
+	SourceCommandLine = parser.SourceCommandLine // the command line arguments
+	SourceEnvironment = parser.SourceEnvironment // an environment variable
+	SourceConfigFile  = parser.SourceConfigFile  // the config file
+)
+
+// Returns whether the argument or option was set when calling [Parse]. The
+// name is the name of the argument or option, or the short form of an
+// option. For arguments and options of a command, the name should be
+// preceded by the command names, separated by spaces, like
+// `IsSet("update upgrade all")`.
+func IsSet(name string) bool {
+	if parsed == nil {
+		return false
+	}
+	return parsed.IsSet(name)
+}
+
+// Returns where the value of the argument or option came from when calling
+// [Parse]. The name is written the same way as for [IsSet]. If the value
+// wasn't set, [SourceDefault] is returned.
+func Source(name string) ValueSource {
+	if parsed == nil {
+		return SourceDefault
+	}
+	return parsed.Source(name)
+}
+
+// Returns the names of the commands that were selected when calling [Parse],
+// for instance `[]string{"update", "upgrade"}` for `./program update
+// upgrade`. If no command was selected, the slice is empty.
+func CommandPath() []string {
+	if parsed == nil {
+		return []string{}
+	}
+	return parsed.CommandPath()
 }
@@ -0,0 +1,160 @@
+package applause
+
+import (
+	"context"
+	"errors"
+)
+
+// Implemented by args and command structs that can be run by [Execute].
+type Runner interface {
+	Run(ctx context.Context) error
+}
+
+// Implemented by args and command structs that need to run code before the
+// selected command is run by [Execute], for instance to check credentials.
+type PreRunner interface {
+	PreRun(ctx context.Context) error
+}
+
+// Implemented by args and command structs that need to run code after the
+// selected command is run by [Execute] without an error.
+type PostRunner interface {
+	PostRun(ctx context.Context) error
+}
+
+// Returned by [Execute] when the arguments couldn't be parsed.
+type ParseError struct {
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Returns 2, the exit code for incorrect usage.
+func (e *ParseError) ExitCode() int {
+	return 2
+}
+
+// The context key for the structs of the commands that were run
+type commandsKey struct{}
+
+/*
+Parses the arguments into the args struct like [Parse], and then calls the
+Run method of the struct for the command that was selected, so the caller
+doesn't have to check which command was used. For instance, running
+`./program update upgrade` calls the Run method of the `upgrade` command
+struct. If no command was selected, the Run method of the args struct is
+called. If the struct doesn't implement [Runner], its help text is printed.
+
+Commands of type bool don't have their own struct, so the Run method of the
+struct they're declared in is called instead.
+
+Before Run is called, the PreRun methods of the args struct and the
+commands that were selected are called in order, starting with the args
+struct, if they implement [PreRunner]. After Run returns without an error,
+their PostRun methods are called in the same order if they implement
+[PostRunner]. If any of them returns an error, it is returned.
+
+The options of the parent commands can be accessed in a Run method with
+[Parent]. If the arguments couldn't be parsed, a [*ParseError] is returned,
+otherwise the error returned by Run is returned. If an external command was
+run instead, see [ExternalCommands], nil is returned if it succeeded, and an
+[*ExternalCommandError] otherwise. [ExitCode] can be used to
+get the exit code for the error:
+
+	if err := applause.Execute(ctx, &args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(applause.ExitCode(err))
+	}
+*/
+func Execute(ctx context.Context, args any, options ...Option) error {
+	if err := Parse(args, options...); err != nil {
+		// the external command was run instead
+		var external *ExternalCommandError
+		if errors.As(err, &external) {
+			if external.Code == 0 {
+				return nil
+			}
+			return external
+		}
+		return &ParseError{Err: err}
+	}
+
+	commands := []any{}
+	leaf := parsed
+	for p := parsed; p != nil; p = p.Subcommand {
+		commands = append(commands, p.Config.Interface())
+		leaf = p
+	}
+
+	runner, ok := commands[len(commands)-1].(Runner)
+	if !ok {
+		leaf.PrintHelp()
+		return nil
+	}
+	ctx = context.WithValue(ctx, commandsKey{}, commands)
+
+	for _, command := range commands {
+		if hook, ok := command.(PreRunner); ok {
+			if err := hook.PreRun(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	if err := runner.Run(ctx); err != nil {
+		return err
+	}
+	for _, command := range commands {
+		if hook, ok := command.(PostRunner); ok {
+			if err := hook.PostRun(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the struct of type T for the parent command, or for the args
+// struct, of the command being run by [Execute]. The nearest parent of the
+// type is returned, or nil if there isn't one.
+//
+//	func (u *Upgrade) Run(ctx context.Context) error {
+//		args := applause.Parent[Args](ctx)
+//		if args.Verbose {
+//			// ...
+//		}
+//	}
+func Parent[T any](ctx context.Context) *T {
+	commands, _ := ctx.Value(commandsKey{}).([]any)
+	if len(commands) == 0 {
+		return nil
+	}
+
+	// the last struct is the command being run
+	for i := len(commands) - 2; i >= 0; i-- {
+		if command, ok := commands[i].(*T); ok {
+			return command
+		}
+	}
+	return nil
+}
+
+// Returns the exit code for an error returned by [Execute]. If the error is
+// nil, the exit code is 0. If the error, or an error it wraps, has an
+// `ExitCode() int` method, like [*ParseError], the exit code is the value it
+// returns. Otherwise, the exit code is 1.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var coder interface{ ExitCode() int }
+	if errors.As(err, &coder) {
+		return coder.ExitCode()
+	}
+	return 1
+}
@@ -0,0 +1,102 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// Decodes JSON config files, keeping numbers as [json.Number] so large
+// integers aren't rounded
+func decodeJSON(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// Removes the `--config` option from the arguments and loads the config file
+// it points to, or the default config file if it isn't set
+func (p *Parser) loadConfig() error {
+	p.ConfigVals = map[string]any{}
+
+	path := ""
+	explicit := false
+	for i := 0; i < len(p.Arguments); i++ {
+		arg := p.Arguments[i]
+		if arg == "--" {
+			break
+		}
+		if val, ok := strings.CutPrefix(arg, "--config="); ok {
+			path = val
+			explicit = true
+			p.Arguments = append(p.Arguments[:i:i], p.Arguments[i+1:]...)
+			i--
+			continue
+		}
+		if arg == "--config" {
+			if len(p.Arguments) <= i+1 {
+				return fmt.Errorf("Value not provided for option `%s`", arg)
+			}
+			path = p.Arguments[i+1]
+			explicit = true
+			p.Arguments = append(p.Arguments[:i:i], p.Arguments[i+2:]...)
+			i--
+		}
+	}
+
+	if !explicit {
+		path = p.Settings.ConfigFile
+	}
+	if path == "" {
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return nil
+		}
+		path = filepath.Join(configDir, p.Name, "config.json")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("Error reading config file: %v", err)
+	}
+
+	ext := strings.ToLower(filepath.Ext(path))
+	decode, ok := p.Settings.ConfigDecoders[ext]
+	if !ok && ext == ".json" {
+		decode = decodeJSON
+	}
+	if decode == nil {
+		return fmt.Errorf("Error reading config file: No decoder for `%s` files", ext)
+	}
+
+	if err := decode(data, &p.ConfigVals); err != nil {
+		return fmt.Errorf("Error reading config file: %v", err)
+	}
+	return nil
+}
+
+// Returns the values from the config file for the named command
+func (p *Parser) commandConfig(name string) map[string]any {
+	if vals, ok := p.ConfigVals[name].(map[string]any); ok {
+		return vals
+	}
+	return map[string]any{}
+}
+
+// Converts a value decoded from the config file to the option type
+func (o option) parseConfigValue(configVal any) (reflect.Value, error) {
+	switch configVal.(type) {
+	case map[string]any, []any:
+		return reflect.Value{}, fmt.Errorf("Invalid value for option `%s`: %v", o.displayName(), configVal)
+	}
+	return o.parseValue(fmt.Sprint(configVal))
+}
@@ -0,0 +1,113 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+)
+
+// Returns whether unknown commands are run as external commands, which is
+// only done for the root command
+func (p *Parser) externalCommandsEnabled() bool {
+	return p.Settings.ExternalCommands && p.Parent == nil
+}
+
+// Returns the names of the external commands found on `PATH`, like `foo` for
+// an executable named `<name>-foo`. Commands with the same name as one of
+// the command's own commands are left out.
+func (p *Parser) findExternalCommands() []string {
+	if !p.externalCommandsEnabled() {
+		return []string{}
+	}
+
+	prefix := p.Name + "-"
+	names := []string{}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name, ok := strings.CutPrefix(entry.Name(), prefix)
+			if !ok || name == "" || slices.Contains(names, name) || p.FindComandByName(name) != -1 {
+				continue
+			}
+			info, err := os.Stat(filepath.Join(dir, entry.Name()))
+			if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 {
+				continue
+			}
+			names = append(names, name)
+		}
+	}
+
+	slices.Sort(names)
+	return names
+}
+
+// Wrapped by every [ExternalCommandError], so `errors.Is` can check whether
+// an external command was run, including when it succeeded
+var ErrExternalCommand = errors.New("An external command was run")
+
+// Returned by `Parse` when an external command was run, as the arguments
+// were handled by the external command
+type ExternalCommandError struct {
+	Command string // name of the external command, without the program name
+	Code    int    // exit code of the external command
+}
+
+func (e *ExternalCommandError) Error() string {
+	return fmt.Sprintf("External command `%s` exited with code %d", e.Command, e.Code)
+}
+
+func (e *ExternalCommandError) Unwrap() error {
+	return ErrExternalCommand
+}
+
+// Returns the exit code of the external command
+func (e *ExternalCommandError) ExitCode() int {
+	return e.Code
+}
+
+// Runs the external command named `<name>-<command>` with the arguments if
+// it's found on `PATH`, returning an [ExternalCommandError] with its exit
+// code. If it isn't found, nothing is done and nil is returned.
+func (p *Parser) runExternalCommand(command string, args []string) error {
+	if command == "" || command[0] == '-' || strings.ContainsAny(command, `/\`) {
+		return nil
+	}
+	path, err := exec.LookPath(p.Name + "-" + command)
+	if err != nil {
+		return nil
+	}
+
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = os.Environ()
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return fmt.Errorf("Error running external command `%s`: %v", command, err)
+		}
+		return &ExternalCommandError{Command: command, Code: exitCode(exitErr)}
+	}
+	return &ExternalCommandError{Command: command, Code: 0}
+}
+
+// Returns the exit code of the process like a shell does, which is 128 plus
+// the signal number if it was killed by a signal
+func exitCode(err *exec.ExitError) int {
+	if code := err.ExitCode(); code != -1 {
+		return code
+	}
+	if status, ok := err.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return 1
+}
@@ -26,9 +26,24 @@ func (p *Parser) generateZshCompletions(indent int) string {
 	indentLarge := strings.Repeat(" ", indent+4)
 	indentXL := strings.Repeat(" ", indent+6)
 
-	options := make([]string, len(p.Options))
-	for i, opt := range p.Options {
-		if opt.Type.Kind() == reflect.Bool {
+	allOptions := activeOptions(slices.Concat(p.Options, p.globalOptions()))
+	options := make([]string, len(allOptions))
+	for i, opt := range allOptions {
+		if isBool(opt.Type) {
+			if opt.Negatable {
+				exclusions := fmt.Sprintf("--%[1]s --no-%[1]s", opt.Name)
+				if opt.Short != "" {
+					exclusions = fmt.Sprintf("-%s %s", opt.Short, exclusions)
+					options[i] = fmt.Sprintf(
+						"'(%[1]s)'{-%[2]s,--%[3]s}'[%[4]s]' '(%[1]s)--no-%[3]s[%[4]s]'",
+						exclusions, opt.Short, opt.Name, opt.Help)
+					continue
+				}
+				options[i] = fmt.Sprintf(
+					"'(%[1]s)--%[2]s[%[3]s]' '(%[1]s)--no-%[2]s[%[3]s]'",
+					exclusions, opt.Name, opt.Help)
+				continue
+			}
 			if opt.Short != "" {
 				options[i] = fmt.Sprintf(
 					"'(-%[1]s --%[2]s)'{-%[1]s,--%[2]s}'[%s]'",
@@ -40,7 +55,16 @@ func (p *Parser) generateZshCompletions(indent int) string {
 		}
 
 		completion := fmt.Sprintf("--%s[%s]:%[1]s:", opt.Name, opt.Help)
-		if opt.Short != "" {
+		if opt.Optional {
+			// the value can only be given with `--name=value`, so the short
+			// form is completed as a flag
+			completion = fmt.Sprintf("--%s=-[%s]:%[1]s:", opt.Name, opt.Help)
+			if opt.Short != "" {
+				completion = fmt.Sprintf(
+					"(-%[1]s --%[2]s)-%[1]s[%[3]s]' '(-%[1]s --%[2]s)--%[2]s=-[%[3]s]:%[2]s:",
+					opt.Short, opt.Name, opt.Help)
+			}
+		} else if opt.Short != "" {
 			completion = fmt.Sprintf(
 				"(-%[1]s --%[2]s)'{-%[1]s,--%[2]s}'[%s]:%[2]s:",
 				opt.Short, opt.Name, opt.Help)
@@ -75,17 +99,22 @@ func (p *Parser) generateZshCompletions(indent int) string {
 		}
 		options[i] = fmt.Sprintf(`'%s'`, completion)
 	}
-	if len(p.Commands) == 0 {
-		posAndOpts := fmt.Sprintf(
-			"%[1]s_arguments '(-h --help)'{-h,--help}'[Display this help and exit.]' %s",
-			indentSmall, strings.Join(options, " "))
+	if p.Settings.ConfigEnabled {
+		options = append(options, "'--config[Load options from a config file.]:path:_files'")
+	}
+	active := activeCommands(p.Commands)
+	externals := p.findExternalCommands()
+	if len(active) == 0 && len(externals) == 0 {
 		posCompletions := make([]string, 0, len(p.Positionals))
 		for i, pos := range p.Positionals {
-			if pos.Completion == "" {
+			if pos.Completion == "" || pos.Deprecated != "" {
 				continue
 			}
 			completion := fmt.Sprintf("%d:%s:", i+1, pos.Name)
-			if pos.Type.Kind() == reflect.Slice {
+			if isOptional(pos.Type) {
+				completion = fmt.Sprintf("%d::%s:", i+1, pos.Name)
+			}
+			if isVariadic(pos.Type) {
 				completion = fmt.Sprintf("*:%s:", pos.Name)
 			}
 			if pos.Completion == "files" || strings.HasPrefix(pos.Completion, "files[") {
@@ -115,23 +144,29 @@ func (p *Parser) generateZshCompletions(indent int) string {
 			}
 			posCompletions = append(posCompletions, fmt.Sprintf(`'%s'`, completion))
 		}
-		posAndOpts += strings.Join(posCompletions, " ")
+		posAndOpts := fmt.Sprintf(
+			"%[1]s_arguments '(-h --help)'{-h,--help}'[Display this help and exit.]' %s",
+			indentSmall, strings.Join(slices.Concat(options, posCompletions), " "))
 		return strings.TrimSpace(posAndOpts)
 	}
 
-	commands := make([]string, len(p.Commands))
-	commandCompletions := make([]string, len(p.Commands))
-	for i, cmd := range p.Commands {
+	commands := make([]string, len(active))
+	commandCompletions := make([]string, len(active))
+	for i, cmd := range active {
 		commands[i] = fmt.Sprintf("'%s[%s]'", cmd.Name, cmd.Help)
+		names := strings.Join(append([]string{cmd.Name}, cmd.Aliases...), "|")
 		if cmd.Value.Elem().Kind() == reflect.Bool {
 			commandCompletions[i] = fmt.Sprintf(
 				"%s%s) _arguments '(-h --help)'{-h,--help}'[Display this help and exit.]' ;;",
-				indentLarge, cmd.Name)
+				indentLarge, names)
 			continue
 		}
-		cmdParser := NewParser(p.Name+" "+cmd.Name, []string{}, cmd.Value)
+		cmdParser := p.newCommandParser(cmd, []string{})
 		completions := cmdParser.generateZshCompletions(indent + 6)
-		commandCompletions[i] = fmt.Sprintf("%[1]s%[2]s) %[4]s ;;", indentLarge, cmd.Name, indentXL, completions)
+		commandCompletions[i] = fmt.Sprintf("%[1]s%[2]s) %[4]s ;;", indentLarge, names, indentXL, completions)
+	}
+	for _, external := range externals {
+		commands = append(commands, fmt.Sprintf("'%s[External command]'", external))
 	}
 
 	args := ";;"
@@ -2,13 +2,22 @@ package parser
 
 import (
 	"fmt"
-	"reflect"
+	"slices"
 	"strings"
 )
 
+// Prints the help text, with the external commands if they're enabled
+func (p *Parser) PrintHelp() {
+	if p.externalCommandsEnabled() {
+		p.generateHelp(p.findExternalCommands())
+	}
+	fmt.Println(p.Help)
+}
+
 func (p *Parser) generateUsage() {
-	commandNames := make([]string, len(p.Commands))
-	for i, command := range p.Commands {
+	commands := activeCommands(p.Commands)
+	commandNames := make([]string, len(commands))
+	for i, command := range commands {
 		commandNames[i] = command.Name
 	}
 	commandUsage := fmt.Sprintf("[%s]", strings.Join(commandNames, " | "))
@@ -21,10 +30,14 @@ func (p *Parser) generateUsage() {
 
 	positionalUsage := ""
 	for _, positional := range p.Positionals {
-		if positional.Type.Kind() == reflect.Slice {
+		if isVariadic(positional.Type) {
 			positionalUsage += fmt.Sprintf("[%s...] ", positional.Name)
 			continue
 		}
+		if isOptional(positional.Type) {
+			positionalUsage += fmt.Sprintf("[%s] ", positional.Name)
+			continue
+		}
 		positionalUsage += fmt.Sprintf("<%s> ", positional.Name)
 	}
 	positionalUsage = strings.TrimSpace(positionalUsage)
@@ -33,61 +46,74 @@ func (p *Parser) generateUsage() {
 	}
 
 	optionUsage := ""
-	for _, option := range p.Options {
-		optionUsagePart := "["
-		if option.Name != "" {
-			optionUsagePart += fmt.Sprintf("--%s", option.Name)
-		} else {
-			if option.Short == "" {
-				continue
+	options := activeOptions(p.Options)
+	groupsDone := []string{}
+	for _, option := range options {
+		if !p.isExclusiveGroup(option.Group) {
+			if part := formatOptionUsage(option); part != "" {
+				optionUsage += fmt.Sprintf("[%s] ", part)
 			}
-			optionUsagePart += fmt.Sprintf("-%s", option.Short)
+			continue
+		}
+
+		// options that cannot be used together are displayed as `[--a | --b]`
+		if slices.Contains(groupsDone, option.Group) {
+			continue
 		}
-		if option.Value != "" {
-			optionUsagePart += fmt.Sprintf(" <%s>", option.Value)
+		groupsDone = append(groupsDone, option.Group)
+		parts := []string{}
+		for _, other := range options {
+			if other.Group == option.Group {
+				parts = append(parts, formatOptionUsage(other))
+			}
 		}
-		optionUsagePart += "] "
-		optionUsage += optionUsagePart
+		optionUsage += fmt.Sprintf("[%s] ", strings.Join(parts, " | "))
 	}
 	optionUsage = strings.TrimSpace(optionUsage)
 
 	p.Usage = fmt.Sprintf("USAGE: %s %s%s%s", p.Name, commandUsage, positionalUsage, optionUsage)
 }
 
-func (p *Parser) generateHelp() {
+// Generates the help text. The external commands are only listed when the
+// help text is printed, as finding them means reading every directory on
+// `PATH`.
+func (p *Parser) generateHelp(externals []string) {
 	maxLen := 10 // length of `-h, --help`
-	for _, command := range p.Commands {
-		maxLen = max(len(command.Name), maxLen)
+	commands := visibleCommands(p.Commands)
+	for _, command := range commands {
+		maxLen = max(len(formatCommand(command)), maxLen)
+	}
+	for _, external := range externals {
+		maxLen = max(len(external), maxLen)
 	}
 	for _, positional := range p.Positionals {
 		maxLen = max(len(positional.Name)+2, maxLen) // add 2 for `<>`
-		if positional.Type.Kind() == reflect.Slice {
+		if isVariadic(positional.Type) {
 			maxLen += 3 // add 3 for `...`
 		}
 	}
-	for _, option := range p.Options {
-		optLen := 0
-		if option.Name != "" {
-			optLen += len(option.Name) + 2 // add 2 for `--`
-		}
-		if option.Value != "" {
-			optLen += len(option.Value) + 3 // add 3 for ` <>`
-		}
-		if option.Short != "" {
-			optLen += len(option.Short) + 3 // add 3 for `-, `
-		}
-		maxLen = max(optLen, maxLen)
+	options := visibleOptions(p.Options)
+	globals := visibleOptions(p.globalOptions())
+	for _, option := range slices.Concat(options, globals) {
+		maxLen = max(len(formatOption(option)), maxLen)
+	}
+	if p.Settings.ConfigEnabled {
+		maxLen = max(15, maxLen) // length of `--config <path>`
 	}
 
 	commandHelp := ""
-	for _, command := range p.Commands {
+	for _, command := range commands {
 		if commandHelp == "" {
 			commandHelp = "\nCOMMANDS:\n"
 		}
 		help := wrapLines(command.Help, maxLen)
+		if command.Default {
+			help += " (default)"
+		}
+		name := formatCommand(command)
 		commandHelp += fmt.Sprintf(
-			"  %s%s        %s\n",
-			command.Name, strings.Repeat(" ", maxLen-len(command.Name)), help,
+			"  %s%s        %s%s\n",
+			name, strings.Repeat(" ", maxLen-len(name)), help, deprecation(command.Deprecated),
 		)
 	}
 	commandHelp = strings.TrimSpace(commandHelp)
@@ -95,22 +121,43 @@ func (p *Parser) generateHelp() {
 		commandHelp += "\n\n"
 	}
 
+	externalHelp := ""
+	if len(externals) > 0 {
+		externalHelp = "EXTERNAL COMMANDS:\n"
+		for _, external := range externals {
+			externalHelp += fmt.Sprintf("  %s\n", external)
+		}
+		externalHelp += "\n"
+	}
+
 	positionalHelp := ""
 	for _, positional := range p.Positionals {
 		if positionalHelp == "" {
 			positionalHelp = "\nARGUMENTS:\n"
 		}
 		help := wrapLines(positional.Help, maxLen)
-		if positional.Type.Kind() == reflect.Slice {
+		choices := ""
+		if len(positional.Choices) > 0 {
+			choices = fmt.Sprintf(" (possible values: %s)", strings.Join(positional.Choices, ", "))
+		}
+		choices += positional.hint() + deprecation(positional.Deprecated)
+		if isVariadic(positional.Type) {
+			positionalHelp += fmt.Sprintf(
+				"  [%s...]%s        %s%s\n",
+				positional.Name, strings.Repeat(" ", maxLen-len(positional.Name)-5), help, choices,
+			)
+			continue
+		}
+		if isOptional(positional.Type) {
 			positionalHelp += fmt.Sprintf(
-				"  [%s...]%s        %s\n",
-				positional.Name, strings.Repeat(" ", maxLen-len(positional.Name)-5), help,
+				"  [%s]%s        %s%s\n",
+				positional.Name, strings.Repeat(" ", maxLen-len(positional.Name)-2), help, choices,
 			)
 			continue
 		}
 		positionalHelp += fmt.Sprintf(
-			"  <%s>%s        %s\n",
-			positional.Name, strings.Repeat(" ", maxLen-len(positional.Name)-2), help,
+			"  <%s>%s        %s%s\n",
+			positional.Name, strings.Repeat(" ", maxLen-len(positional.Name)-2), help, choices,
 		)
 	}
 	positionalHelp = strings.TrimSpace(positionalHelp)
@@ -119,45 +166,138 @@ func (p *Parser) generateHelp() {
 	}
 
 	optionHelp := "OPTIONS:\n"
-	for _, option := range p.Options {
-		optLen := 0
-		name := ""
-		if option.Name != "" {
-			name = fmt.Sprintf("--%s", option.Name)
-			optLen += len(option.Name) + 2 // add `--`
-		}
-		short := ""
-		if option.Short != "" {
-			if option.Name == "" {
-				short = fmt.Sprintf("-%s", option.Short)
-				optLen += len(option.Short) + 1 // add `-`
-			} else {
-				short = fmt.Sprintf("-%s, ", option.Short)
-				optLen += len(option.Short) + 3 // add `-, `
+	sections := []string{}
+	for _, option := range options {
+		if option.Section != "" {
+			if !slices.Contains(sections, option.Section) {
+				sections = append(sections, option.Section)
 			}
+			continue
 		}
-		value := ""
-		if option.Value != "" {
-			value = fmt.Sprintf(" <%s>", option.Value)
-			optLen += len(option.Value) + 3 // add ` <>`
-		}
-		defaultStr := ""
-		if !option.Default.IsZero() {
-			defaultStr = fmt.Sprintf(" (default: %v)", option.Default)
-		}
-		help := wrapLines(option.Help, maxLen)
-		optionHelp += fmt.Sprintf(
-			"  %s%s%s%s        %s%s\n",
-			short, name, value, strings.Repeat(" ", maxLen-optLen), help, defaultStr,
-		)
+		optionHelp += formatOptionHelp(option, maxLen)
+	}
+	if p.Settings.ConfigEnabled {
+		optionHelp += fmt.Sprintf("  --config <path>%s        Load options from a config file.\n", strings.Repeat(" ", maxLen-15))
 	}
 	optionHelp += fmt.Sprintf("  -h, --help%s        Display this help and exit.", strings.Repeat(" ", maxLen-10))
 	optionHelp = strings.TrimSpace(optionHelp)
 
-	help := fmt.Sprintf("%s\n\n%s%s%s", p.Usage, commandHelp, positionalHelp, optionHelp)
+	// options with a section are displayed under its heading, in the order
+	// the sections are first used
+	sectionHelp := ""
+	for _, section := range sections {
+		sectionHelp += fmt.Sprintf("\n\n%s:\n", strings.ToUpper(section))
+		for _, option := range options {
+			if option.Section == section {
+				sectionHelp += formatOptionHelp(option, maxLen)
+			}
+		}
+		sectionHelp = strings.TrimRight(sectionHelp, "\n")
+	}
+
+	globalHelp := ""
+	for _, option := range globals {
+		if globalHelp == "" {
+			globalHelp = "\n\nGLOBAL OPTIONS:\n"
+		}
+		globalHelp += formatOptionHelp(option, maxLen)
+	}
+
+	help := fmt.Sprintf("%s\n\n%s%s%s%s%s%s", p.Usage, commandHelp, externalHelp, positionalHelp, optionHelp, sectionHelp, globalHelp)
 	p.Help = strings.TrimSpace(help)
 }
 
+// Returns the option as it's displayed in the usage, like `--option <value>`,
+// or an empty string if the option has no name
+func formatOptionUsage(option option) string {
+	usage := ""
+	if option.Negatable {
+		usage = fmt.Sprintf("--[no-]%s", option.Name)
+	} else if option.Name != "" {
+		usage = fmt.Sprintf("--%s", option.Name)
+	} else {
+		if option.Short == "" {
+			return ""
+		}
+		usage = fmt.Sprintf("-%s", option.Short)
+	}
+	if option.Value != "" {
+		if option.Optional {
+			usage += fmt.Sprintf("[=<%s>]", option.Value)
+		} else {
+			usage += fmt.Sprintf(" <%s>", option.Value)
+		}
+	}
+	return usage
+}
+
+// Returns the command as it's displayed in the help text, like
+// `uninstall (aliases: rm, del)`
+func formatCommand(command command) string {
+	if len(command.Aliases) == 0 {
+		return command.Name
+	}
+	return fmt.Sprintf("%s (aliases: %s)", command.Name, strings.Join(command.Aliases, ", "))
+}
+
+// Returns the option as it's displayed in the help text, like `-o, --option <value>`
+func formatOption(option option) string {
+	short := ""
+	if option.Short != "" {
+		if option.Name == "" {
+			short = fmt.Sprintf("-%s", option.Short)
+		} else {
+			short = fmt.Sprintf("-%s, ", option.Short)
+		}
+	}
+	name := ""
+	if option.Name != "" {
+		name = fmt.Sprintf("--%s", option.Name)
+	}
+	if option.Negatable {
+		name = fmt.Sprintf("--[no-]%s", option.Name)
+	}
+	value := ""
+	if option.Value != "" {
+		value = fmt.Sprintf(" <%s>", option.Value)
+	}
+	if option.Value != "" && option.Optional {
+		value = fmt.Sprintf("[=<%s>]", option.Value)
+	}
+	return short + name + value
+}
+
+// Returns the line for the option in the help text
+func formatOptionHelp(option option, maxLen int) string {
+	aliasStr := ""
+	if len(option.Aliases) > 0 {
+		aliasStr = fmt.Sprintf(" (aliases: --%s)", strings.Join(option.Aliases, ", --"))
+	}
+	envStr := ""
+	if option.Env != "" {
+		envStr = fmt.Sprintf(" (env: %s)", option.Env)
+	}
+	defaultStr := ""
+	if !option.Default.IsZero() {
+		defaultStr = fmt.Sprintf(" (default: %s)", option.format(option.Default))
+	}
+	opt := formatOption(option)
+	help := wrapLines(option.Help, maxLen)
+	return fmt.Sprintf(
+		"  %s%s        %s%s%s%s%s%s\n",
+		opt, strings.Repeat(" ", maxLen-len(opt)), help, option.hint(), aliasStr, envStr, defaultStr, deprecation(option.Deprecated),
+	)
+}
+
+// Returns the note shown in the help text for a deprecated item, or an empty
+// string if the message is empty
+func deprecation(message string) string {
+	if message == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (deprecated: %s)", message)
+}
+
 func wrapLines(help string, maxLen int) string {
 	if len(help) <= 80 {
 		return help
@@ -0,0 +1,43 @@
+package parser
+
+import (
+	"reflect"
+)
+
+// Implemented by config structs that need to run code before their
+// arguments are parsed
+type BeforeParser interface {
+	BeforeParse()
+}
+
+// Implemented by config structs that need to check or change their values
+// after the arguments are parsed
+type AfterParser interface {
+	AfterParse() error
+}
+
+// Calls the BeforeParse method of the config struct if it has one
+func beforeParse(config reflect.Value) {
+	if config.Kind() == reflect.Pointer && config.IsNil() {
+		return
+	}
+	if hook, ok := config.Interface().(BeforeParser); ok {
+		hook.BeforeParse()
+	}
+}
+
+// Calls the AfterParse methods of the config structs for this command and
+// the commands that were run, from the root command to the last command
+func (p *Parser) AfterParse() error {
+	for ; p != nil; p = p.Subcommand {
+		if p.Config.Kind() == reflect.Pointer && p.Config.IsNil() {
+			continue
+		}
+		if hook, ok := p.Config.Interface().(AfterParser); ok {
+			if err := hook.AfterParse(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
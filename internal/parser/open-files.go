@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"io"
+	"os"
+	"reflect"
+
+	"github.com/noclaps/applause/internal/utils"
+)
+
+// Opens the files for the positionals and options of this command and the
+// commands that were run, and sets them in the config structs. This is done
+// once parsing has succeeded, so files aren't created if parsing fails. If
+// a file can't be opened, the files that were already opened are closed.
+func (p *Parser) OpenFiles() error {
+	opened := []io.Closer{}
+	closeAll := func() {
+		for _, f := range opened {
+			f.Close()
+		}
+	}
+
+	open := func(path string, fileType reflect.Type) (reflect.Value, error) {
+		val, err := utils.OpenFile(path, fileType)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if f, ok := val.Interface().(*os.File); ok && f != os.Stdin && f != os.Stdout {
+			opened = append(opened, f)
+		}
+		return val, nil
+	}
+
+	for ; p != nil; p = p.Subcommand {
+		fields := map[string][]int{}
+		types := map[string]reflect.Type{}
+		for _, pos := range p.Positionals {
+			fields[pos.Name], types[pos.Name] = pos.Index, pos.Type
+		}
+		for _, option := range p.Options {
+			fields[option.Name], types[option.Name] = option.Index, option.Type
+		}
+
+		for name, parsedVal := range p.ParsedVals {
+			fileType, ok := types[name]
+			if !ok || !isFile(fileType) {
+				continue
+			}
+
+			field := p.Config.Elem().FieldByIndex(fields[name])
+			if !isVariadic(fileType) {
+				val, err := open(parsedVal.String(), fileType)
+				if err != nil {
+					closeAll()
+					return err
+				}
+				field.Set(val)
+				continue
+			}
+
+			slice := reflect.MakeSlice(fileType, 0, parsedVal.Len())
+			for i := range parsedVal.Len() {
+				val, err := open(parsedVal.Index(i).String(), fileType.Elem())
+				if err != nil {
+					closeAll()
+					return err
+				}
+				slice = reflect.Append(slice, val)
+			}
+			field.Set(slice)
+		}
+	}
+	return nil
+}
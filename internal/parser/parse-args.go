@@ -3,6 +3,7 @@ package parser
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"reflect"
 	"slices"
@@ -22,154 +23,223 @@ func (p *Parser) parseOptions() error {
 			return nil
 		}
 
-		// long option
-		if len(arg) > 2 && arg[:2] == "--" {
-			// --key=val
-			if si := strings.Index(arg, "="); si != -1 {
-				key := arg[2:si]
-				val := arg[si+1:]
-				optIndex := p.FindOptionByName(key)
-				if optIndex == -1 {
-					return fmt.Errorf("`%s` is not a recognised option.", arg[:si])
-				}
+		if len(arg) > 1 && arg[0] == '-' {
+			consumed, err := p.parseOption(i)
+			if err != nil {
+				return err
+			}
+			i += consumed - 1
+			continue
+		}
 
-				parsedVal, err := utils.ValToType(val, p.Options[optIndex].Type)
-				if err != nil {
-					return err
-				}
+		arguments = append(arguments, arg)
+	}
 
-				p.ParsedVals[key] = parsedVal
-				continue
-			}
+	p.Arguments = arguments
+	return nil
+}
 
-			// --key val
-			key := arg[2:]
-			optIndex := p.FindOptionByName(key)
-			if optIndex == -1 {
-				return fmt.Errorf("`%s` is not a recognised option", arg)
-			}
-			if p.Options[optIndex].Type.Kind() == reflect.Bool {
-				p.ParsedVals[key] = reflect.ValueOf(true)
-				continue
+// Parses the options before the first argument that isn't an option, like
+// the options before a command name, and removes them from the arguments
+func (p *Parser) parseLeadingOptions() error {
+	i := 0
+	for i < len(p.Arguments) {
+		arg := p.Arguments[i]
+		if len(arg) < 2 || arg[0] != '-' || arg == "--" || arg == "-h" || arg == "--help" {
+			break
+		}
+		// options that aren't recognised are left for the default command
+		if p.FindDefaultCommand() != -1 && !p.isOption(strings.SplitN(arg, "=", 2)[0]) {
+			if _, negIndex := p.lookupOption(Parser.FindOptionByNegation, strings.TrimPrefix(arg, "--")); negIndex == -1 {
+				break
 			}
+		}
 
-			if len(p.Arguments) <= i+1 {
-				return fmt.Errorf("Value not provided for option `%s`", arg)
-			}
-			val := p.Arguments[i+1]
-			if (len(val) > 2 && p.FindOptionByName(val[2:]) != -1) || (len(val) > 1 && p.FindOptionByShort(val[1:]) != -1) {
-				return fmt.Errorf("Value not provided for option `%s`", arg)
+		consumed, err := p.parseOption(i)
+		if err != nil {
+			return err
+		}
+		i += consumed
+	}
+
+	p.Arguments = p.Arguments[i:]
+	return nil
+}
+
+// Parses the option at the index in the arguments, returning the number of
+// arguments used by the option and its value
+func (p *Parser) parseOption(i int) (int, error) {
+	arg := p.Arguments[i]
+
+	// long option
+	if len(arg) > 2 && arg[:2] == "--" {
+		// --key=val
+		if si := strings.Index(arg, "="); si != -1 {
+			key := arg[2:si]
+			val := arg[si+1:]
+			owner, optIndex := p.lookupOption(Parser.FindOptionByName, key)
+			if optIndex == -1 {
+				return 0, fmt.Errorf("`%s` is not a recognised option.", arg[:si])
 			}
 
-			parsedVal, err := utils.ValToType(val, p.Options[optIndex].Type)
+			parsedVal, err := owner.Options[optIndex].parseValue(val)
 			if err != nil {
-				return err
+				return 0, err
 			}
 
-			p.ParsedVals[key] = parsedVal
-			i++
-			continue
+			owner.ParsedVals[owner.Options[optIndex].Name] = parsedVal
+			return 1, nil
 		}
 
-		// short option
-		if len(arg) > 1 && arg[0] == '-' {
-			optionName := arg[1:]
-			optIndex := p.FindOptionByShort(optionName)
-			if optIndex == -1 {
-				return fmt.Errorf("`%s` is not a recognised option.", arg)
-			}
-			name := p.Options[optIndex].Name
-			if p.Options[optIndex].Type.Kind() == reflect.Bool {
-				p.ParsedVals[name] = reflect.ValueOf(true)
-				continue
+		// --key val
+		key := arg[2:]
+		owner, optIndex := p.lookupOption(Parser.FindOptionByName, key)
+		if optIndex == -1 {
+			// --no-key
+			if negOwner, negIndex := p.lookupOption(Parser.FindOptionByNegation, key); negIndex != -1 {
+				negOwner.ParsedVals[negOwner.Options[negIndex].Name] = boolValue(negOwner.Options[negIndex].Type, false)
+				return 1, nil
 			}
+			return 0, fmt.Errorf("`%s` is not a recognised option", arg)
+		}
+		return p.parseOptionValue(i, owner, owner.Options[optIndex])
+	}
 
-			if len(p.Arguments) == i+1 {
-				return fmt.Errorf("Value not provided for option `%s`", arg)
-			}
-			val := p.Arguments[i+1]
-			if (len(val) > 2 && p.FindOptionByName(val[2:]) != -1) || (len(val) > 1 && p.FindOptionByShort(val[1:]) != -1) {
-				return fmt.Errorf("Value not provided for option `%s`", arg)
-			}
+	// short option
+	optionName := arg[1:]
+	owner, optIndex := p.lookupOption(Parser.FindOptionByShort, optionName)
+	if optIndex == -1 {
+		return 0, fmt.Errorf("`%s` is not a recognised option.", arg)
+	}
+	return p.parseOptionValue(i, owner, owner.Options[optIndex])
+}
 
-			parsedVal, err := utils.ValToType(val, p.Options[optIndex].Type)
-			if err != nil {
-				return err
-			}
+// Parses the value for the option at the index in the arguments, which is
+// either the next argument or implied by the option, and sets it on the
+// parser the option belongs to
+func (p *Parser) parseOptionValue(i int, owner *Parser, option option) (int, error) {
+	arg := p.Arguments[i]
 
-			p.ParsedVals[name] = parsedVal
-			i++
-			continue
+	if isBool(option.Type) {
+		owner.ParsedVals[option.Name] = boolValue(option.Type, true)
+		return 1, nil
+	}
+	if option.Optional {
+		parsedVal, err := option.parseValue(option.Implied)
+		if err != nil {
+			return 0, err
 		}
 
-		arguments = append(arguments, arg)
+		owner.ParsedVals[option.Name] = parsedVal
+		return 1, nil
 	}
 
-	p.Arguments = arguments
-	return nil
+	if len(p.Arguments) <= i+1 {
+		return 0, fmt.Errorf("Value not provided for option `%s`", arg)
+	}
+	val := p.Arguments[i+1]
+	if p.isOption(val) {
+		return 0, fmt.Errorf("Value not provided for option `%s`", arg)
+	}
+
+	parsedVal, err := option.parseValue(val)
+	if err != nil {
+		return 0, err
+	}
+
+	owner.ParsedVals[option.Name] = parsedVal
+	return 2, nil
 }
 
 func (p *Parser) parsePositionals() error {
-	currentPosCounter := 0
-	for i := 0; i < len(p.Arguments); i++ {
-		arg := p.Arguments[i]
-
-		if currentPosCounter == len(p.Positionals) {
-			return fmt.Errorf("Extra argument: `%s`", arg)
+	required := 0
+	for _, pos := range p.Positionals {
+		if !isVariadic(pos.Type) && !isOptional(pos.Type) {
+			required++
 		}
+	}
+	if len(p.Arguments) < required {
+		return fmt.Errorf("Not enough arguments provided.")
+	}
 
-		currentPos := p.Positionals[currentPosCounter]
-		name := currentPos.Name
-
+	// arguments left over after the required positionals, given to optional
+	// positionals in order and then to multiple arguments
+	extra := len(p.Arguments) - required
+	i := 0
+	for _, pos := range p.Positionals {
 		// Multiple arguments
-		if currentPos.Type.Kind() == reflect.Slice {
-			slice := reflect.MakeSlice(currentPos.Type, 0, len(p.Positionals)-currentPosCounter-1)
-			posType := currentPos.Type.Elem()
-
-			for ; len(p.Arguments)-i != len(p.Positionals)-currentPosCounter-1; i++ {
-				arg = p.Arguments[i]
+		if isVariadic(pos.Type) {
+			if extra == 0 {
+				continue
+			}
 
-				val, err := utils.ValToType(arg, posType)
+			sliceType := pos.Type
+			if isFile(pos.Type) {
+				// the paths are kept until the files are opened
+				sliceType = reflect.TypeFor[[]string]()
+			}
+			slice := reflect.MakeSlice(sliceType, 0, extra)
+			for ; extra > 0; extra-- {
+				val, err := pos.parseValue(p.Arguments[i], pos.Type.Elem())
 				if err != nil {
 					return err
 				}
 
 				slice = reflect.Append(slice, val)
+				i++
 			}
 
-			p.ParsedVals[name] = slice
-			currentPosCounter++
-			i--
+			p.ParsedVals[pos.Name] = slice
 			continue
 		}
 
-		// Read from stdin
-		if arg == "-" {
-			scanner := bufio.NewScanner(os.Stdin)
-			scanner.Scan()
-			stdinVal := scanner.Text()
+		if isOptional(pos.Type) {
+			if extra == 0 {
+				continue
+			}
+			extra--
+		}
 
-			val, err := utils.ValToType(stdinVal, currentPos.Type)
+		arg := p.Arguments[i]
+		i++
+
+		// Read from stdin
+		if arg == "-" && !utils.IsFileType(pos.Type) {
+			stdinVal, err := readStdin(pos.ReadAll)
 			if err != nil {
 				return err
 			}
-
-			p.ParsedVals[name] = val
-			currentPosCounter++
-			continue
+			arg = stdinVal
 		}
 
-		val, err := utils.ValToType(arg, currentPos.Type)
+		val, err := pos.parseValue(arg, pos.Type)
 		if err != nil {
 			return err
 		}
 
-		p.ParsedVals[name] = val
-		currentPosCounter++
+		p.ParsedVals[pos.Name] = val
 	}
-	if currentPosCounter < len(p.Positionals) {
-		return fmt.Errorf("Not enough arguments provided.")
+	if i < len(p.Arguments) {
+		return fmt.Errorf("Extra argument: `%s`", p.Arguments[i])
 	}
 
 	return nil
 }
+
+// Reads a single line from stdin, or the whole of stdin if readAll is true
+func readStdin(readAll bool) (string, error) {
+	if readAll {
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("Error reading from stdin: %v", err)
+		}
+		return string(b), nil
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Scan()
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("Error reading from stdin: %v", err)
+	}
+	return scanner.Text(), nil
+}
@@ -2,45 +2,90 @@ package parser
 
 import (
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"reflect"
 	"slices"
+	"strings"
 )
 
 type Parser struct {
 	Name           string        // command name
 	Arguments      []string      // OS arguments
 	Config         reflect.Value // pointer to config struct
+	Settings       *Settings     // settings shared with nested command parsers
 	Positionals    []positional
 	Options        []option
 	Commands       []command
 	Help           string
 	Usage          string
 	ParsedVals     map[string]reflect.Value
+	Sources        map[string]Source // sources of parsed values not from the command line
+	ConfigVals     map[string]any    // values from the config file for this command
 	AllowEmptyArgs bool
+	Parent         *Parser // parser for the parent command
+	Subcommand     *Parser // parser for the command that was run
+	Command        string  // name of the command that was selected
+	PathField      []int   // index of the field the selected command path is set in
+	err            error   // error from reading the config struct, returned by Parse
+}
+
+// Settings for the parser, set by the options passed to `applause.Parse`
+type Settings struct {
+	ConfigEnabled    bool                               // enable the `--config` option
+	ConfigFile       string                             // default config file path
+	ConfigDecoders   map[string]func([]byte, any) error // config file decoders by file extension
+	ResponseFiles    bool                               // expand `@file` arguments
+	Warnings         io.Writer                          // where warnings are written, stderr if nil
+	StrictCommands   bool                               // only allow command names where there are commands
+	ExternalCommands bool                               // run unknown commands as `<name>-<command>` executables
 }
 
 // config should be a pointer to a struct
-func NewParser(cmdName string, args []string, config reflect.Value) *Parser {
+func NewParser(cmdName string, args []string, config reflect.Value, settings *Settings) *Parser {
 	p := Parser{
 		Name:       cmdName,
 		Arguments:  args,
 		ParsedVals: make(map[string]reflect.Value),
+		Sources:    make(map[string]Source),
 		Config:     config,
+		Settings:   settings,
 	}
+	beforeParse(config)
+	p.init()
+
+	return &p
+}
 
-	if err := p.reflection(); err != nil {
-		log.Println(err)
+// Returns a parser for the command's config struct, with this parser as the parent
+func (p *Parser) newCommandParser(command command, args []string) *Parser {
+	nestedP := Parser{
+		Name:           fmt.Sprintf("%s %s", p.Name, command.Name),
+		Arguments:      args,
+		ParsedVals:     make(map[string]reflect.Value),
+		Sources:        make(map[string]Source),
+		Config:         command.Value,
+		Settings:       p.Settings,
+		AllowEmptyArgs: command.AllowEmptyArgs,
+		Parent:         p,
 	}
+	nestedP.init()
 
-	p.generateUsage()
-	p.generateHelp()
+	return &nestedP
+}
 
-	return &p
+func (p *Parser) init() {
+	p.err = p.reflection()
+
+	p.generateUsage()
+	p.generateHelp([]string{})
 }
 
 func (p *Parser) Parse() error {
+	if p.err != nil {
+		return p.err
+	}
+
 	if i := slices.Index(p.Arguments, "--completions"); i != -1 {
 		shell := ""
 		if len(p.Arguments) > i+1 {
@@ -54,42 +99,61 @@ func (p *Parser) Parse() error {
 		os.Exit(0)
 	}
 
-	if len(p.Commands) > 0 {
-		if (len(p.Arguments) == 0 && !p.AllowEmptyArgs) || p.Arguments[0] == "-h" || p.Arguments[0] == "--help" {
-			fmt.Println(p.Help)
-			os.Exit(0)
+	if err := p.checkRelationNames(); err != nil {
+		return err
+	}
+
+	if p.Settings.ConfigEnabled && p.ConfigVals == nil {
+		if err := p.loadConfig(); err != nil {
+			return err
 		}
-		cIndex := p.FindComandByName(p.Arguments[0])
-		if cIndex != -1 {
-			command := p.Commands[cIndex]
-			if command.Value.Elem().Kind() == reflect.Bool {
-				p.Config.Elem().FieldByName(command.StructName).SetBool(true)
-				return nil
-			}
+	}
 
-			if command.AllowEmptyArgs {
-				emptyStruct := reflect.New(command.Value.Type().Elem())
-				p.Config.Elem().FieldByName(command.StructName).Set(emptyStruct)
+	noArgs := len(p.Arguments) == 0
+	if len(p.Commands) > 0 || p.externalCommandsEnabled() {
+		defaultIndex := p.FindDefaultCommand()
+		if noArgs && !p.AllowEmptyArgs && defaultIndex == -1 {
+			p.PrintHelp()
+			os.Exit(0)
+		}
+		if err := p.parseLeadingOptions(); err != nil {
+			return err
+		}
+		if len(p.Arguments) > 0 && (p.Arguments[0] == "-h" || p.Arguments[0] == "--help") {
+			p.PrintHelp()
+			os.Exit(0)
+		}
 
-				if len(p.Arguments[1:]) == 0 {
-					return nil
+		if len(p.Arguments) > 0 {
+			if cIndex := p.FindComandByName(p.Arguments[0]); cIndex != -1 {
+				return p.runCommand(p.Commands[cIndex], p.Arguments[1:])
+			}
+			if p.externalCommandsEnabled() {
+				if err := p.runExternalCommand(p.Arguments[0], p.Arguments[1:]); err != nil {
+					return err
 				}
 			}
-
-			nestedCmdName := fmt.Sprintf("%s %s", p.Name, command.Name)
-			nestedP := NewParser(nestedCmdName, p.Arguments[1:], command.Value)
-			nestedP.AllowEmptyArgs = command.AllowEmptyArgs
-			return nestedP.Parse()
+		}
+		// the default command gets all of the arguments, as the first
+		// argument isn't a command name
+		if defaultIndex != -1 {
+			return p.runCommand(p.Commands[defaultIndex], p.Arguments)
 		}
 	}
 
-	if (len(p.Arguments) == 0 && !p.AllowEmptyArgs) || slices.ContainsFunc(p.Arguments, func(arg string) bool {
+	if (noArgs && !p.AllowEmptyArgs) || slices.ContainsFunc(p.Arguments, func(arg string) bool {
 		return arg == "--help" || arg == "-h"
 	}) {
-		fmt.Println(p.Help)
+		p.PrintHelp()
 		os.Exit(0)
 	}
 
+	// the first argument isn't a command, so it's an argument unless there
+	// are no arguments or only commands are allowed
+	if (len(p.Commands) > 0 || p.externalCommandsEnabled()) && len(p.Arguments) > 0 && p.Arguments[0] != "--" && (len(p.Positionals) == 0 || p.Settings.StrictCommands) {
+		return fmt.Errorf("`%s` is not a recognised command.", p.Arguments[0])
+	}
+
 	if err := p.parseOptions(); err != nil {
 		return err
 	}
@@ -97,16 +161,146 @@ func (p *Parser) Parse() error {
 		return err
 	}
 
+	return p.apply()
+}
+
+// Parses the arguments for the command, and sets the command in the config
+// struct
+func (p *Parser) runCommand(command command, args []string) error {
+	p.Command = command.Name
+	if command.Deprecated != "" {
+		p.warn("Command `%s` is deprecated: %s", command.Name, command.Deprecated)
+	}
+	if command.Value.Elem().Kind() == reflect.Bool {
+		p.Config.Elem().FieldByIndex(command.Index).SetBool(true)
+
+		// bool commands don't take arguments, but options can still be given
+		p.Arguments = args
+		if err := p.parseOptions(); err != nil {
+			return err
+		}
+		if len(p.Arguments) > 0 {
+			return fmt.Errorf("Extra argument: `%s`", p.Arguments[0])
+		}
+		return p.apply()
+	}
+
+	if command.AllowEmptyArgs {
+		emptyStruct := reflect.New(command.Value.Type().Elem())
+		p.Config.Elem().FieldByIndex(command.Index).Set(emptyStruct)
+	}
+
+	beforeParse(command.Value)
+	nestedP := p.newCommandParser(command, args)
+	nestedP.ConfigVals = p.commandConfig(command.Name)
+	// the default command is run without arguments when none are given
+	nestedP.AllowEmptyArgs = command.AllowEmptyArgs || command.Default
+	p.Subcommand = nestedP
+	if command.AllowEmptyArgs && len(args) == 0 {
+		if nestedP.err != nil {
+			return nestedP.err
+		}
+		if err := nestedP.checkRelationNames(); err != nil {
+			return err
+		}
+		if err := nestedP.apply(); err != nil {
+			return err
+		}
+		return p.apply()
+	}
+	if err := nestedP.Parse(); err != nil {
+		return err
+	}
+	return p.apply()
+}
+
+// Fills in options that weren't set on the command line from the environment
+// and the config file, and sets the parsed values in the config struct
+func (p *Parser) apply() error {
+	for _, option := range p.Options {
+		if _, ok := p.ParsedVals[option.Name]; ok {
+			continue
+		}
+
+		if env, ok := os.LookupEnv(option.Env); ok && option.Env != "" {
+			val, err := option.parseValue(env)
+			if err != nil {
+				return fmt.Errorf("Error in environment variable `%s`: %v", option.Env, err)
+			}
+			p.ParsedVals[option.Name] = val
+			p.Sources[option.Name] = SourceEnvironment
+			continue
+		}
+
+		if configVal, ok := p.ConfigVals[option.Name]; ok && option.Name != "" {
+			val, err := option.parseConfigValue(configVal)
+			if err != nil {
+				return fmt.Errorf("Error in config file: %v", err)
+			}
+			p.ParsedVals[option.Name] = val
+			p.Sources[option.Name] = SourceConfigFile
+		}
+	}
+
+	p.warnDeprecated()
+
+	if p.PathField != nil {
+		field := p.Config.Elem().FieldByIndex(p.PathField)
+		if field.Kind() == reflect.String {
+			field.SetString(strings.Join(p.CommandPath(), " "))
+		} else {
+			field.Set(reflect.ValueOf(p.CommandPath()))
+		}
+	}
+
+	// files are set by `OpenFiles` once parsing has succeeded
 	for k, v := range p.ParsedVals {
 		if posIndex := p.FindPositionalByName(k); posIndex != -1 {
 			positional := p.Positionals[posIndex]
-			p.Config.Elem().FieldByName(positional.StructName).Set(v)
+			if !isFile(positional.Type) {
+				p.Config.Elem().FieldByIndex(positional.Index).Set(v)
+			}
 		}
 		if optIndex := p.FindOptionByName(k); optIndex != -1 {
 			option := p.Options[optIndex]
-			p.Config.Elem().FieldByName(option.StructName).Set(v)
+			if !isFile(option.Type) {
+				p.Config.Elem().FieldByIndex(option.Index).Set(v)
+			}
 		}
 	}
 
 	return nil
 }
+
+// Writes a warning for each deprecated positional and option that was set
+func (p *Parser) warnDeprecated() {
+	for _, pos := range p.Positionals {
+		if _, ok := p.ParsedVals[pos.Name]; ok && pos.Deprecated != "" {
+			p.warn("Argument `<%s>` is deprecated: %s", pos.Name, pos.Deprecated)
+		}
+	}
+	for _, option := range p.Options {
+		if _, ok := p.ParsedVals[option.Name]; ok && option.Deprecated != "" {
+			p.warn("Option `%s` is deprecated: %s", option.displayName(), option.Deprecated)
+		}
+	}
+}
+
+// Writes a warning to the writer set in the settings, or to stderr
+func (p *Parser) warn(format string, args ...any) {
+	w := p.Settings.Warnings
+	if w == nil {
+		w = os.Stderr
+	}
+	fmt.Fprintf(w, "Warning: "+format+"\n", args...)
+}
+
+// Returns the names of the commands that were selected below this command,
+// like `[]string{"update", "upgrade"}`
+func (p *Parser) CommandPath() []string {
+	path := []string{}
+	for ; p != nil && p.Command != ""; p = p.Subcommand {
+		path = append(path, p.Command)
+	}
+	return path
+}
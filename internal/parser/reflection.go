@@ -3,6 +3,10 @@ package parser
 import (
 	"fmt"
 	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/noclaps/applause/internal/utils"
 )
@@ -12,50 +16,171 @@ func (p *Parser) reflection() error {
 	if !config.IsValid() {
 		config = reflect.Zero(p.Config.Type().Elem())
 	}
-	configType := config.Type()
 
-	positionalsConf := []positional{}
-	optionsConf := []option{}
-	commandsConf := []command{}
+	p.Positionals = []positional{}
+	p.Options = []option{}
+	p.Commands = []command{}
+	if err := p.reflectFields(config, []int{}, "", ""); err != nil {
+		return err
+	}
+	defaults := slices.DeleteFunc(slices.Clone(p.Commands), func(c command) bool {
+		return !c.Default
+	})
+	if len(defaults) > 1 {
+		return fmt.Errorf("Error in field `%s`: Only one command can be the default, but `%s` is also the default.", defaults[1].StructName, defaults[0].StructName)
+	}
+	return p.checkDuplicateNames()
+}
+
+// Returns an error if two options or two commands have the same name, which
+// can happen when the same struct is embedded more than once
+func (p *Parser) checkDuplicateNames() error {
+	longs := map[string]string{}
+	shorts := map[string]string{}
+	for _, option := range p.Options {
+		for _, name := range append([]string{option.Name}, option.Aliases...) {
+			if other, ok := longs[name]; ok {
+				return fmt.Errorf("Error in field `%s`: `--%s` is used by both `--%s` and `--%s`.", option.StructName, name, other, option.Name)
+			}
+			longs[name] = option.Name
+		}
+		if option.Short == "" {
+			continue
+		}
+		if other, ok := shorts[option.Short]; ok {
+			return fmt.Errorf("Error in field `%s`: `-%s` is used by both `--%s` and `--%s`. Short names aren't prefixed, so they must be unique.", option.StructName, option.Short, other, option.Name)
+		}
+		shorts[option.Short] = option.Name
+	}
+
+	commands := map[string]string{}
+	for _, command := range p.Commands {
+		for _, name := range append([]string{command.Name}, command.Aliases...) {
+			if other, ok := commands[name]; ok {
+				return fmt.Errorf("Error in field `%s`: `%s` is used by both the `%s` and `%s` commands.", command.StructName, name, other, command.Name)
+			}
+			commands[name] = command.Name
+		}
+	}
+	return nil
+}
+
+// Adds the arguments, options and commands for the fields of the struct. The
+// fields of embedded structs are added as if they were fields of the struct,
+// with the prefix added to their names and the section set for their options.
+func (p *Parser) reflectFields(config reflect.Value, index []int, prefix string, section string) error {
+	configType := config.Type()
 
 	for i := range config.NumField() {
 		field := configType.Field(i)
+		fieldIndex := append(slices.Clone(index), i)
+
+		if isEmbedded(field) {
+			embedSection := section
+			if s, ok := field.Tag.Lookup("section"); ok {
+				embedSection = s
+			}
+			embedded := config.Field(i)
+			if embedded.Kind() == reflect.Pointer {
+				if embedded.IsNil() && embedded.CanSet() {
+					embedded.Set(reflect.New(field.Type.Elem()))
+				}
+				switch {
+				case !embedded.IsNil():
+					embedded = embedded.Elem()
+				case config.CanAddr():
+					return fmt.Errorf("Error in field `%s`: Embedded pointers to unexported structs must be set before parsing.", field.Name)
+				default:
+					// the parent struct isn't set, so there are no values to use
+					embedded = reflect.Zero(field.Type.Elem())
+				}
+			}
+			if err := p.reflectFields(embedded, fieldIndex, prefix+field.Tag.Get("prefix"), embedSection); err != nil {
+				return err
+			}
+			continue
+		}
 		if !field.IsExported() {
 			continue
 		}
 
+		if field.Tag.Get("type") == "command-path" {
+			if field.Type != reflect.TypeFor[string]() && field.Type != reflect.TypeFor[[]string]() {
+				return fmt.Errorf("Error in field `%s`: Fields with `type:\"command-path\"` must be of type `string` or `[]string`.", field.Name)
+			}
+			p.PathField = fieldIndex
+			continue
+		}
+
 		fieldName := utils.PascalToKebabCase(field.Name)
 		if name, ok := field.Tag.Lookup("name"); ok {
 			fieldName = name
 		}
+		if fieldName != "" {
+			fieldName = prefix + fieldName
+		}
 
-		if field.Type.Kind() == reflect.Pointer && field.Type.Elem().Kind() == reflect.Struct {
-			commandsConf = append(commandsConf, command{
+		if field.Type.Kind() == reflect.Pointer && field.Type.Elem().Kind() == reflect.Struct && !utils.IsCustomType(field.Type) && !utils.IsCustomType(field.Type.Elem()) {
+			p.Commands = append(p.Commands, command{
 				StructName:     field.Name,
+				Index:          fieldIndex,
 				Name:           fieldName,
 				Value:          config.Field(i),
 				Help:           field.Tag.Get("help"),
 				AllowEmptyArgs: true,
+				Aliases:        splitTag(field.Tag.Get("aliases")),
+				Hidden:         field.Tag.Get("hidden") == "true",
+				Deprecated:     field.Tag.Get("deprecated"),
+				Default:        field.Tag.Get("default") == "true",
 			})
 			continue
 		}
-		if field.Type.Kind() == reflect.Struct || (field.Tag.Get("type") == "command" && field.Type.Kind() == reflect.Bool) {
-			commandsConf = append(commandsConf, command{
+		if (field.Type.Kind() == reflect.Struct && !utils.IsCustomType(field.Type)) || (field.Tag.Get("type") == "command" && field.Type.Kind() == reflect.Bool) {
+			p.Commands = append(p.Commands, command{
 				StructName: field.Name,
+				Index:      fieldIndex,
 				Name:       fieldName,
 				Value:      config.Field(i).Addr(),
 				Help:       field.Tag.Get("help"),
+				Aliases:    splitTag(field.Tag.Get("aliases")),
+				Hidden:     field.Tag.Get("hidden") == "true",
+				Deprecated: field.Tag.Get("deprecated"),
+				Default:    field.Tag.Get("default") == "true",
 			})
 			continue
 		}
 
+		valConf, err := fieldValueConfig(field)
+		if err != nil {
+			return err
+		}
+		completion := field.Tag.Get("completion")
+		if completion == "" {
+			completion = strings.Join(valConf.Choices, " ")
+		}
+		if completion == "" && utils.IsFileType(field.Type) {
+			completion = "files"
+		}
+
 		if field.Tag.Get("type") == "arg" || field.Tag.Get("type") == "" {
-			positionalsConf = append(positionalsConf, positional{
-				StructName: field.Name,
-				Name:       fieldName,
-				Type:       field.Type,
-				Completion: field.Tag.Get("completion"),
-				Help:       field.Tag.Get("help"),
+			readAll := false
+			if stdin, ok := field.Tag.Lookup("stdin"); ok {
+				if stdin != "all" && stdin != "line" {
+					return fmt.Errorf("Error in field `%s`: `stdin` must be either `line` or `all`.", field.Name)
+				}
+				readAll = stdin == "all"
+			}
+
+			p.Positionals = append(p.Positionals, positional{
+				StructName:  field.Name,
+				Index:       fieldIndex,
+				Name:        fieldName,
+				Type:        field.Type,
+				Completion:  completion,
+				Help:        field.Tag.Get("help"),
+				ReadAll:     readAll,
+				Deprecated:  field.Tag.Get("deprecated"),
+				valueConfig: valConf,
 			})
 			continue
 		}
@@ -67,35 +192,165 @@ func (p *Parser) reflection() error {
 			if fieldName == "help" {
 				return fmt.Errorf("Error in field `%s`: Field name cannot be `Help` as this is reserved for the `--help` option.", field.Name)
 			}
+			if fieldName == "config" && p.Settings.ConfigEnabled {
+				return fmt.Errorf("Error in field `%s`: Field name cannot be `Config` as this is reserved for the `--config` option.", field.Name)
+			}
 			if field.Tag.Get("short") == "h" {
 				return fmt.Errorf("Error in field `%s`: Field short cannot be `h` as this is reserved for the `--help` option.", field.Name)
 			}
+			negatable := field.Tag.Get("negatable") == "true"
+			if negatable && !isBool(field.Type) {
+				return fmt.Errorf("Error in field `%s`: Only fields of type `bool` can be negatable.", field.Name)
+			}
+			if negatable && fieldName == "" {
+				return fmt.Errorf("Error in field `%s`: Negatable options must have a name.", field.Name)
+			}
+
+			implied, optional := field.Tag.Lookup("implied")
+			if optional && isBool(field.Type) {
+				return fmt.Errorf("Error in field `%s`: Fields of type `bool` cannot have an implied value.", field.Name)
+			}
 
 			fieldValue := utils.PascalToKebabCase(field.Name)
+			if len(valConf.Choices) > 0 {
+				fieldValue = strings.Join(valConf.Choices, "|")
+			}
 			if v, ok := field.Tag.Lookup("value"); ok {
 				fieldValue = v
 			}
-			if field.Type.Kind() == reflect.Bool {
+			if isBool(field.Type) {
 				fieldValue = ""
 			}
 
 			defaultVal := config.Field(i)
 
-			optionsConf = append(optionsConf, option{
-				StructName: field.Name,
-				Name:       fieldName,
-				Type:       field.Type,
-				Value:      fieldValue,
-				Help:       field.Tag.Get("help"),
-				Short:      field.Tag.Get("short"),
-				Default:    defaultVal,
-				Completion: field.Tag.Get("completion"),
+			optionSection := section
+			if s, ok := field.Tag.Lookup("section"); ok {
+				optionSection = s
+			}
+
+			p.Options = append(p.Options, option{
+				StructName:  field.Name,
+				Index:       fieldIndex,
+				Name:        fieldName,
+				Type:        field.Type,
+				Value:       fieldValue,
+				Help:        field.Tag.Get("help"),
+				Short:       field.Tag.Get("short"),
+				Default:     defaultVal,
+				Completion:  completion,
+				Negatable:   negatable,
+				Optional:    optional,
+				Implied:     implied,
+				Env:         field.Tag.Get("env"),
+				Persistent:  field.Tag.Get("persistent") == "true",
+				Aliases:     splitTag(field.Tag.Get("aliases")),
+				Hidden:      field.Tag.Get("hidden") == "true",
+				Deprecated:  field.Tag.Get("deprecated"),
+				Group:       field.Tag.Get("group"),
+				Exclusive:   field.Tag.Get("exclusive") == "true",
+				Conflicts:   splitTag(field.Tag.Get("conflicts")),
+				Requires:    splitTag(field.Tag.Get("requires")),
+				Section:     optionSection,
+				valueConfig: valConf,
 			})
 		}
 	}
 
-	p.Positionals = positionalsConf
-	p.Options = optionsConf
-	p.Commands = commandsConf
 	return nil
 }
+
+// Returns whether the fields of the struct field should be added to the
+// parent struct instead of it being a command
+func isEmbedded(field reflect.StructField) bool {
+	structType := field.Type
+	if structType.Kind() == reflect.Pointer && !utils.IsCustomType(structType) {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct || utils.IsCustomType(structType) {
+		return false
+	}
+	if field.Anonymous {
+		return true
+	}
+	return field.IsExported() && (field.Tag.Get("embed") == "true" || field.Tag.Get("type") == "group")
+}
+
+// Returns the value configuration set by the `choices`, `min`, `max`,
+// `pattern`, `layout` and `unit` tags. If the `choices` tag is omitted, the
+// values of the field type are used if it implements [Enum].
+func fieldValueConfig(field reflect.StructField) (valueConfig, error) {
+	c := valueConfig{}
+
+	valType := field.Type
+	if isVariadic(valType) || isOptional(valType) {
+		valType = valType.Elem()
+	}
+
+	if tag, ok := field.Tag.Lookup("choices"); ok {
+		c.Choices = splitTag(tag)
+	} else {
+		c.Choices = enumValues(valType)
+	}
+
+	if tag, ok := field.Tag.Lookup("pattern"); ok {
+		if valType.Kind() != reflect.String {
+			return c, fmt.Errorf("Error in field `%s`: `pattern` can only be set on string fields.", field.Name)
+		}
+		pattern, err := regexp.Compile(tag)
+		if err != nil {
+			return c, fmt.Errorf("Error in field `%s`: Invalid `pattern` value: %v", field.Name, err)
+		}
+		c.Pattern = pattern
+	}
+
+	if tag, ok := field.Tag.Lookup("layout"); ok {
+		if valType != reflect.TypeFor[time.Time]() {
+			return c, fmt.Errorf("Error in field `%s`: `layout` can only be set on `time.Time` fields.", field.Name)
+		}
+		c.Layout = tag
+	}
+
+	if tag, ok := field.Tag.Lookup("unit"); ok {
+		if tag != "bytes" {
+			return c, fmt.Errorf("Error in field `%s`: `unit` must be `bytes`.", field.Name)
+		}
+		if !isInteger(valType) {
+			return c, fmt.Errorf("Error in field `%s`: `unit` can only be set on integer fields.", field.Name)
+		}
+		c.Size = true
+	}
+
+	// bounds are parsed last so they can use the layout and unit
+	for _, bound := range []string{"min", "max"} {
+		tag, ok := field.Tag.Lookup(bound)
+		if !ok {
+			continue
+		}
+		if !isNumber(valType) {
+			return c, fmt.Errorf("Error in field `%s`: `%s` can only be set on numeric fields.", field.Name, bound)
+		}
+		val, err := c.convert(tag, valType)
+		if err != nil {
+			return c, fmt.Errorf("Error in field `%s`: Invalid `%s` value: %v", field.Name, bound, err)
+		}
+		if bound == "min" {
+			c.Min = val
+		} else {
+			c.Max = val
+		}
+	}
+
+	return c, nil
+}
+
+// Returns the values of a comma-separated tag, like `choices:"red,green"`
+func splitTag(tag string) []string {
+	values := strings.Split(tag, ",")
+	for i, value := range values {
+		values[i] = strings.TrimSpace(value)
+	}
+	return slices.DeleteFunc(values, func(value string) bool {
+		return value == ""
+	})
+}
@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"fmt"
+)
+
+// Returns an error if the `conflicts` or `requires` tag of an option names an
+// option that doesn't exist. The persistent options of parent commands can
+// be named as well.
+func (p *Parser) checkRelationNames() error {
+	for _, option := range p.Options {
+		for _, name := range option.Conflicts {
+			if _, i := p.lookupOption(Parser.FindOptionByName, name); i == -1 {
+				return fmt.Errorf("Error in field `%s`: `conflicts` refers to unknown option `%s`.", option.StructName, name)
+			}
+		}
+		for _, name := range option.Requires {
+			if _, i := p.lookupOption(Parser.FindOptionByName, name); i == -1 {
+				return fmt.Errorf("Error in field `%s`: `requires` refers to unknown option `%s`.", option.StructName, name)
+			}
+		}
+	}
+	return nil
+}
+
+// Checks the relations between the options of this command and the commands
+// that were run, once all of the values have been set
+func (p *Parser) CheckRelations() error {
+	for ; p != nil; p = p.Subcommand {
+		if err := p.checkRelations(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns whether the options in the group cannot be used together
+func (p Parser) isExclusiveGroup(group string) bool {
+	if group == "" {
+		return false
+	}
+	for _, option := range p.Options {
+		if option.Group == group && option.Exclusive {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns an error if options that cannot be used together were set on the
+// command line, or if an option was set without the options it requires.
+// Options with unknown names are skipped, as they're reported by
+// `checkRelationNames`.
+func (p *Parser) checkRelations() error {
+	isSet := func(owner *Parser, option option) bool {
+		_, ok := owner.ParsedVals[option.Name]
+		return ok
+	}
+	fromCommandLine := func(owner *Parser, option option) bool {
+		_, elsewhere := owner.Sources[option.Name]
+		return isSet(owner, option) && !elsewhere
+	}
+
+	for i, option := range p.Options {
+		if !fromCommandLine(p, option) {
+			continue
+		}
+
+		if p.isExclusiveGroup(option.Group) {
+			for _, other := range p.Options[i+1:] {
+				if other.Group == option.Group && fromCommandLine(p, other) {
+					return fmt.Errorf("`%s` cannot be used with `%s`.", option.displayName(), other.displayName())
+				}
+			}
+		}
+
+		for _, name := range option.Conflicts {
+			owner, otherIndex := p.lookupOption(Parser.FindOptionByName, name)
+			if otherIndex == -1 {
+				continue
+			}
+			other := owner.Options[otherIndex]
+			if fromCommandLine(owner, other) {
+				return fmt.Errorf("`%s` cannot be used with `%s`.", option.displayName(), other.displayName())
+			}
+		}
+	}
+
+	for _, option := range p.Options {
+		if !isSet(p, option) {
+			continue
+		}
+		for _, name := range option.Requires {
+			owner, otherIndex := p.lookupOption(Parser.FindOptionByName, name)
+			if otherIndex == -1 {
+				continue
+			}
+			other := owner.Options[otherIndex]
+			if !isSet(owner, other) {
+				return fmt.Errorf("`%s` requires `%s`.", option.displayName(), other.displayName())
+			}
+		}
+	}
+
+	return nil
+}
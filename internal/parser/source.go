@@ -0,0 +1,81 @@
+package parser
+
+import "strings"
+
+// Where the value of an argument or option came from
+type Source int
+
+const (
+	SourceDefault     Source = iota // the value in the struct before parsing
+	SourceCommandLine               // the command line arguments
+	SourceEnvironment               // an environment variable
+	SourceConfigFile                // the config file
+)
+
+func (s Source) String() string {
+	switch s {
+	case SourceCommandLine:
+		return "command line"
+	case SourceEnvironment:
+		return "environment"
+	case SourceConfigFile:
+		return "config file"
+	}
+	return "default"
+}
+
+// Returns the parser for the commands in the path and the name of the
+// argument or option at the end of it. The path is the names of the commands
+// followed by the name of the argument or option, separated by spaces, like
+// `update upgrade all`.
+func (p *Parser) resolve(path string) (*Parser, string) {
+	names := strings.Fields(path)
+	if len(names) == 0 {
+		return p, ""
+	}
+
+	for _, command := range names[:len(names)-1] {
+		if cIndex := p.FindComandByName(command); cIndex != -1 {
+			command = p.Commands[cIndex].Name
+		}
+		if p.Subcommand == nil || p.Subcommand.Name != p.Name+" "+command {
+			return nil, ""
+		}
+		p = p.Subcommand
+	}
+
+	// options can belong to a parent command if they're persistent
+	name := names[len(names)-1]
+	if p.FindPositionalByName(name) != -1 {
+		return p, name
+	}
+	if owner, optIndex := p.lookupOption(Parser.FindOptionByName, name); optIndex != -1 {
+		return owner, owner.Options[optIndex].Name
+	}
+	if owner, optIndex := p.lookupOption(Parser.FindOptionByShort, name); optIndex != -1 {
+		return owner, owner.Options[optIndex].Name
+	}
+	return p, name
+}
+
+// Returns whether the argument or option at the path was set when parsing
+func (p *Parser) IsSet(path string) bool {
+	p, name := p.resolve(path)
+	if p == nil {
+		return false
+	}
+	_, ok := p.ParsedVals[name]
+	return ok
+}
+
+// Returns where the value of the argument or option at the path came from
+func (p *Parser) Source(path string) Source {
+	if !p.IsSet(path) {
+		return SourceDefault
+	}
+	p, name := p.resolve(path)
+	if source, ok := p.Sources[name]; ok {
+		return source
+	}
+	return SourceCommandLine
+}
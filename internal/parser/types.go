@@ -2,19 +2,37 @@ package parser
 
 import (
 	"reflect"
+	"regexp"
 	"slices"
+	"strings"
+
+	"github.com/noclaps/applause/internal/utils"
 )
 
+type valueConfig struct {
+	Choices []string       // allowed values
+	Min     reflect.Value  // minimum numeric value
+	Max     reflect.Value  // maximum numeric value
+	Pattern *regexp.Regexp // pattern string values must match
+	Layout  string         // layout for time values
+	Size    bool           // parse integer values as byte sizes
+}
+
 type positional struct {
 	StructName string       // original name in struct
+	Index      []int        // index of the field in the struct
 	Name       string       // positional name
 	Help       string       // positional help
 	Type       reflect.Type // positional type
 	Completion string       // positional completion
+	ReadAll    bool         // read the whole of stdin when the value is `-`
+	Deprecated string       // deprecation message, shown when the positional is used
+	valueConfig
 }
 
 type option struct {
 	StructName string        // original name in struct
+	Index      []int         // index of the field in the struct
 	Name       string        // option name
 	Help       string        // option help
 	Type       reflect.Type  // option type
@@ -22,14 +40,33 @@ type option struct {
 	Default    reflect.Value // option default value
 	Value      string        // option argument name
 	Completion string        // option completion
+	Negatable  bool          // allow `--no-<name>` to set the option to false
+	Optional   bool          // option value may be omitted
+	Implied    string        // option value used when the value is omitted
+	Env        string        // environment variable to read the value from
+	Persistent bool          // option is also accepted by nested commands
+	Aliases    []string      // other names the option can be called with
+	Hidden     bool          // option is left out of help, usage and completions
+	Deprecated string        // deprecation message, shown when the option is used
+	Group      string        // name of the group the option belongs to
+	Exclusive  bool          // options in the group cannot be used together
+	Conflicts  []string      // names of options that cannot be used with this option
+	Requires   []string      // names of options that must be set with this option
+	Section    string        // heading the option is displayed under in the help text
+	valueConfig
 }
 
 type command struct {
 	StructName     string        // original name in struct
+	Index          []int         // index of the field in the struct
 	Name           string        // command name
 	Value          reflect.Value // command value
 	Help           string        // command help
 	AllowEmptyArgs bool          // allow command to be run without args
+	Aliases        []string      // other names the command can be called with
+	Hidden         bool          // command is left out of help, usage and completions
+	Deprecated     string        // deprecation message, shown when the command is used
+	Default        bool          // command is run when the first argument isn't a command
 }
 
 // Returns the index of the named positional, otherwise -1 if the positional doesn't exist
@@ -42,7 +79,7 @@ func (p Parser) FindPositionalByName(name string) int {
 // Returns the index of the named option, otherwise -1 if the option doesn't exist
 func (p Parser) FindOptionByName(name string) int {
 	return slices.IndexFunc(p.Options, func(o option) bool {
-		return o.Name == name
+		return o.Name == name || slices.Contains(o.Aliases, name)
 	})
 }
 
@@ -53,9 +90,129 @@ func (p Parser) FindOptionByShort(short string) int {
 	})
 }
 
+// Returns the parser that has the option and the index of the option, found
+// with one of the `FindOption` methods. If the option isn't found, the
+// persistent options of parent commands are searched. If it still isn't
+// found, the index is -1.
+func (p *Parser) lookupOption(find func(Parser, string) int, name string) (*Parser, int) {
+	if i := find(*p, name); i != -1 {
+		return p, i
+	}
+	for parent := p.Parent; parent != nil; parent = parent.Parent {
+		if i := find(*parent, name); i != -1 && parent.Options[i].Persistent {
+			return parent, i
+		}
+	}
+	return nil, -1
+}
+
+// Returns whether the argument is a recognised option
+func (p *Parser) isOption(arg string) bool {
+	if len(arg) > 2 && arg[:2] == "--" {
+		_, i := p.lookupOption(Parser.FindOptionByName, arg[2:])
+		return i != -1
+	}
+	if len(arg) > 1 && arg[0] == '-' {
+		_, i := p.lookupOption(Parser.FindOptionByShort, arg[1:])
+		return i != -1
+	}
+	return false
+}
+
+// Returns the persistent options of parent commands that are accepted by
+// this command
+func (p *Parser) globalOptions() []option {
+	globals := []option{}
+	for parent := p.Parent; parent != nil; parent = parent.Parent {
+		for _, opt := range parent.Options {
+			if !opt.Persistent || p.FindOptionByName(opt.Name) != -1 {
+				continue
+			}
+			if slices.ContainsFunc(globals, func(o option) bool { return o.Name == opt.Name }) {
+				continue
+			}
+			globals = append(globals, opt)
+		}
+	}
+	return globals
+}
+
+// Returns the options that aren't hidden
+func visibleOptions(options []option) []option {
+	return slices.DeleteFunc(slices.Clone(options), func(o option) bool {
+		return o.Hidden
+	})
+}
+
+// Returns the commands that aren't hidden
+func visibleCommands(commands []command) []command {
+	return slices.DeleteFunc(slices.Clone(commands), func(c command) bool {
+		return c.Hidden
+	})
+}
+
+// Returns the options that aren't hidden or deprecated
+func activeOptions(options []option) []option {
+	return slices.DeleteFunc(visibleOptions(options), func(o option) bool {
+		return o.Deprecated != ""
+	})
+}
+
+// Returns the commands that aren't hidden or deprecated
+func activeCommands(commands []command) []command {
+	return slices.DeleteFunc(visibleCommands(commands), func(c command) bool {
+		return c.Deprecated != ""
+	})
+}
+
 // Returns the index of the named command, otherwise -1 if the command doesn't exist
 func (p Parser) FindComandByName(name string) int {
 	return slices.IndexFunc(p.Commands, func(c command) bool {
-		return c.Name == name
+		return c.Name == name || slices.Contains(c.Aliases, name)
+	})
+}
+
+// Returns the index of the default command, otherwise -1 if there is no default command
+func (p Parser) FindDefaultCommand() int {
+	return slices.IndexFunc(p.Commands, func(c command) bool {
+		return c.Default
+	})
+}
+
+// Returns the index of the negatable option named by `no-<name>`, otherwise -1 if the option doesn't exist
+func (p Parser) FindOptionByNegation(name string) int {
+	name, ok := strings.CutPrefix(name, "no-")
+	if !ok {
+		return -1
+	}
+	return slices.IndexFunc(p.Options, func(o option) bool {
+		return o.Negatable && (o.Name == name || slices.Contains(o.Aliases, name))
 	})
 }
+
+// Returns whether a positional of the type takes multiple arguments
+func isVariadic(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && !utils.IsCustomType(t)
+}
+
+// Returns whether a positional of the type can be omitted, leaving it nil
+func isOptional(t reflect.Type) bool {
+	return t.Kind() == reflect.Pointer && !utils.IsFileType(t)
+}
+
+// Returns whether a positional or option of the type is a file, or multiple
+// files, opened by `OpenFiles`
+func isFile(t reflect.Type) bool {
+	if isVariadic(t) {
+		t = t.Elem()
+	}
+	return utils.IsFileType(t)
+}
+
+// Returns whether an option of the type is a flag that doesn't take a value
+func isBool(t reflect.Type) bool {
+	if isOptional(t) {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Bool
+}
@@ -0,0 +1,209 @@
+package parser
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/noclaps/applause/internal/utils"
+)
+
+// Implemented by types that only accept a fixed set of values
+type Enum interface {
+	Values() []string
+}
+
+// Returns the values accepted by the type if it implements [Enum], otherwise nil
+func enumValues(t reflect.Type) []string {
+	if e, ok := reflect.New(t).Interface().(Enum); ok {
+		return e.Values()
+	}
+	return nil
+}
+
+// Returns the name of the option as it would be written on the command line
+func (o option) displayName() string {
+	if o.Name == "" {
+		return "-" + o.Short
+	}
+	return "--" + o.Name
+}
+
+// Converts the input to the option type, checking it against the option's constraints
+func (o option) parseValue(input string) (reflect.Value, error) {
+	val, err := o.valueConfig.parse(input, o.Type)
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("Invalid value `%s` for option `%s`: %v", input, o.displayName(), err)
+	}
+	return val, nil
+}
+
+// Converts the input to the given type, checking it against the positional's constraints
+func (pos positional) parseValue(input string, valType reflect.Type) (reflect.Value, error) {
+	val, err := pos.valueConfig.parse(input, valType)
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("Invalid value `%s` for argument `<%s>`: %v", input, pos.Name, err)
+	}
+	return val, nil
+}
+
+func (c valueConfig) parse(input string, valType reflect.Type) (reflect.Value, error) {
+	if isOptional(valType) {
+		val, err := c.parse(input, valType.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		ptr := reflect.New(valType.Elem())
+		ptr.Elem().Set(val)
+		return ptr, nil
+	}
+
+	if len(c.Choices) > 0 && !slices.Contains(c.Choices, input) {
+		return reflect.Value{}, fmt.Errorf("must be one of %s", strings.Join(c.Choices, ", "))
+	}
+	if c.Pattern != nil && !c.Pattern.MatchString(input) {
+		return reflect.Value{}, fmt.Errorf("must match the pattern `%s`", c.Pattern)
+	}
+
+	val, err := c.convert(input, valType)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	if c.Min.IsValid() && compareNumbers(val, c.Min) < 0 {
+		return reflect.Value{}, fmt.Errorf("must be at least %v", c.Min)
+	}
+	if c.Max.IsValid() && compareNumbers(val, c.Max) > 0 {
+		return reflect.Value{}, fmt.Errorf("must be at most %v", c.Max)
+	}
+
+	return val, nil
+}
+
+func (c valueConfig) convert(input string, valType reflect.Type) (reflect.Value, error) {
+	// files aren't opened until parsing has succeeded, so the path is kept
+	if utils.IsFileType(valType) {
+		if err := utils.CheckFile(input, valType); err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(input), nil
+	}
+	if c.Layout != "" && valType == reflect.TypeFor[time.Time]() {
+		t, err := time.Parse(c.Layout, input)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("Error parsing %s: %v", valType, err)
+		}
+		return reflect.ValueOf(t), nil
+	}
+	if c.Size && isInteger(valType) {
+		size, err := utils.ParseSize(input)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		val := reflect.New(valType).Elem()
+		if isUnsigned(valType) {
+			if val.OverflowUint(size) {
+				return reflect.Value{}, fmt.Errorf("Error parsing %s: size %q out of range", valType, input)
+			}
+			val.SetUint(size)
+			return val, nil
+		}
+		if size > math.MaxInt64 || val.OverflowInt(int64(size)) {
+			return reflect.Value{}, fmt.Errorf("Error parsing %s: size %q out of range", valType, input)
+		}
+		val.SetInt(int64(size))
+		return val, nil
+	}
+	return utils.ValToType(input, valType)
+}
+
+// Formats the value in the same form it would be parsed from
+func (c valueConfig) format(val reflect.Value) string {
+	if t, ok := val.Interface().(time.Time); ok {
+		layout := time.RFC3339
+		if c.Layout != "" {
+			layout = c.Layout
+		}
+		return t.Format(layout)
+	}
+	if f, ok := val.Interface().(interface{ Name() string }); ok {
+		return f.Name()
+	}
+	if isOptional(val.Type()) && !val.IsNil() {
+		return c.format(val.Elem())
+	}
+	// String methods with pointer receivers, like on url.URL, are only
+	// found through a pointer
+	if val.Kind() != reflect.Pointer && reflect.PointerTo(val.Type()).Implements(reflect.TypeFor[fmt.Stringer]()) {
+		ptr := reflect.New(val.Type())
+		ptr.Elem().Set(val)
+		return ptr.Interface().(fmt.Stringer).String()
+	}
+	return fmt.Sprint(val)
+}
+
+// Returns the constraints as a hint to be displayed in the help text
+func (c valueConfig) hint() string {
+	hints := []string{}
+	if c.Min.IsValid() {
+		hints = append(hints, fmt.Sprintf("min: %v", c.Min))
+	}
+	if c.Max.IsValid() {
+		hints = append(hints, fmt.Sprintf("max: %v", c.Max))
+	}
+	if c.Pattern != nil {
+		hints = append(hints, fmt.Sprintf("pattern: %s", c.Pattern))
+	}
+	if len(hints) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", strings.Join(hints, ", "))
+}
+
+// Compares two numbers of the same kind, returning -1, 0 or 1
+func compareNumbers(a, b reflect.Value) int {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cmp.Compare(a.Uint(), b.Uint())
+	case reflect.Float32, reflect.Float64:
+		return cmp.Compare(a.Float(), b.Float())
+	}
+	return 0
+}
+
+// Returns whether the type is a numeric type that can be compared with [compareNumbers]
+func isNumber(t reflect.Type) bool {
+	return isInteger(t) || t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64
+}
+
+// Returns whether the type is a signed or unsigned integer type
+func isInteger(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return isUnsigned(t)
+}
+
+// Returns whether the type is an unsigned integer type
+func isUnsigned(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// Returns the bool as a value of the type, which is either bool or *bool
+func boolValue(t reflect.Type, b bool) reflect.Value {
+	if isOptional(t) {
+		return reflect.ValueOf(&b).Convert(t)
+	}
+	return reflect.ValueOf(b).Convert(t)
+}
@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Replaces every `@file` argument before `--` with the arguments read from
+// the file. Response files can include other response files, and a `--` in
+// a response file also stops the arguments after it from being expanded.
+func ExpandResponseFiles(args []string) ([]string, error) {
+	expanded, _, err := expandResponseFiles(args, []string{})
+	return expanded, err
+}
+
+// Returns the expanded arguments, and whether `--` was found, either in the
+// arguments or in a response file, so the arguments after it aren't expanded
+func expandResponseFiles(args []string, included []string) ([]string, bool, error) {
+	expanded := make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			return append(expanded, args[i:]...), true, nil
+		}
+		if len(arg) < 2 || arg[0] != '@' {
+			expanded = append(expanded, arg)
+			continue
+		}
+
+		path, err := filepath.Abs(arg[1:])
+		if err != nil {
+			return nil, false, fmt.Errorf("Error reading response file `%s`: %v", arg[1:], err)
+		}
+		if slices.Contains(included, path) {
+			return nil, false, fmt.Errorf("Error reading response file `%s`: File includes itself", arg[1:])
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, false, fmt.Errorf("Error reading response file `%s`: %v", arg[1:], err)
+		}
+		fileArgs, err := SplitWords(string(data))
+		if err != nil {
+			return nil, false, fmt.Errorf("Error reading response file `%s`: %v", arg[1:], err)
+		}
+		fileArgs, stopped, err := expandResponseFiles(fileArgs, append(included, path))
+		if err != nil {
+			return nil, false, err
+		}
+		expanded = append(expanded, fileArgs...)
+		if stopped {
+			return append(expanded, args[i+1:]...), true, nil
+		}
+	}
+	return expanded, false, nil
+}
+
+// Splits the input into words using shell-like rules. Words are separated by
+// whitespace, and whitespace can be kept by quoting it with single or double
+// quotes, or escaping it with a backslash. Inside double quotes, a backslash
+// only escapes `"` and `\`. Words starting with `#` comment out the rest of
+// the line.
+func SplitWords(input string) ([]string, error) {
+	words := []string{}
+	word := strings.Builder{}
+	inWord := false
+
+	runes := []rune(input)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case r == '#' && !inWord:
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '\\':
+			if i+1 < len(runes) {
+				i++
+				if runes[i] != '\n' {
+					word.WriteRune(runes[i])
+					inWord = true
+				}
+			}
+		case r == '\'':
+			end := slices.Index(runes[i+1:], '\'')
+			if end == -1 {
+				return nil, fmt.Errorf("Unterminated single quote")
+			}
+			word.WriteString(string(runes[i+1 : i+1+end]))
+			i += end + 1
+			inWord = true
+		case r == '"':
+			inWord = true
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && (runes[i+1] == '"' || runes[i+1] == '\\') {
+					i++
+				}
+				word.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, fmt.Errorf("Unterminated double quote")
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
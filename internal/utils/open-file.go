@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+)
+
+// Returns whether the type is a file or stream that is opened by [OpenFile]
+func IsFileType(t reflect.Type) bool {
+	return t == reflect.TypeFor[*os.File]() || t == reflect.TypeFor[io.Reader]() || t == reflect.TypeFor[io.Writer]()
+}
+
+// Returns an error if the file at the path can't be opened for reading. Files
+// for [io.Writer] aren't checked, as they're created when they're opened.
+func CheckFile(path string, fileType reflect.Type) error {
+	if fileType == reflect.TypeFor[io.Writer]() || path == "-" {
+		return nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("Error opening file: %v", err)
+	}
+	return nil
+}
+
+// Opens the file at the path for reading, or creates it for writing if the
+// type is [io.Writer]. A path of `-` is stdin, or stdout for [io.Writer].
+func OpenFile(path string, fileType reflect.Type) (reflect.Value, error) {
+	if fileType == reflect.TypeFor[io.Writer]() {
+		if path == "-" {
+			return reflect.ValueOf(os.Stdout).Convert(fileType), nil
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("Error creating file: %v", err)
+		}
+		return reflect.ValueOf(f).Convert(fileType), nil
+	}
+
+	if path == "-" {
+		return reflect.ValueOf(os.Stdin).Convert(fileType), nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("Error opening file: %v", err)
+	}
+	return reflect.ValueOf(f).Convert(fileType), nil
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var sizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1e3,
+	"kb":  1e3,
+	"kib": 1 << 10,
+	"m":   1e6,
+	"mb":  1e6,
+	"mib": 1 << 20,
+	"g":   1e9,
+	"gb":  1e9,
+	"gib": 1 << 30,
+	"t":   1e12,
+	"tb":  1e12,
+	"tib": 1 << 40,
+	"p":   1e15,
+	"pb":  1e15,
+	"pib": 1 << 50,
+}
+
+// Parses a size in bytes with an optional unit suffix, like `512MiB` or
+// `1.5G`. `K`, `M`, `G`, `T` and `P` are powers of 1000, and `KiB`, `MiB`,
+// `GiB`, `TiB` and `PiB` are powers of 1024.
+func ParseSize(input string) (uint64, error) {
+	i := strings.IndexFunc(input, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != '_'
+	})
+	if i == -1 {
+		i = len(input)
+	}
+	number, unit := input[:i], strings.TrimSpace(input[i:])
+
+	multiplier, ok := sizeUnits[strings.ToLower(unit)]
+	if !ok {
+		return 0, fmt.Errorf("Error parsing size %q: unknown unit `%s`", input, unit)
+	}
+
+	if !strings.Contains(number, ".") {
+		number, base := integerBase(number)
+		n, err := strconv.ParseUint(number, base, 64)
+		if err != nil {
+			return 0, fmt.Errorf("Error parsing size %q: %v", input, err)
+		}
+		if n > math.MaxUint64/uint64(multiplier) {
+			return 0, fmt.Errorf("Error parsing size %q: value out of range", input)
+		}
+		return n * uint64(multiplier), nil
+	}
+
+	f, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Error parsing size %q: %v", input, err)
+	}
+	size := f * multiplier
+	if size >= math.MaxUint64 {
+		return 0, fmt.Errorf("Error parsing size %q: value out of range", input)
+	}
+	return uint64(size), nil
+}
@@ -1,19 +1,75 @@
 package utils
 
 import (
+	"encoding"
 	"fmt"
+	"net/url"
 	"reflect"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 )
 
+// Implemented by types that can parse themselves from a command line value
+type Value interface {
+	Set(string) error
+	String() string
+}
+
+// Returns whether the type is parsed by itself through [Value] or
+// [encoding.TextUnmarshaler], or is a supported standard library type,
+// rather than by its kind
+func IsCustomType(t reflect.Type) bool {
+	if t == reflect.TypeFor[url.URL]() || t == reflect.TypeFor[time.Duration]() || t == reflect.TypeFor[[]byte]() || IsFileType(t) {
+		return true
+	}
+	ptr := reflect.PointerTo(t)
+	return ptr.Implements(reflect.TypeFor[Value]()) || ptr.Implements(reflect.TypeFor[encoding.TextUnmarshaler]())
+}
+
 func ValToType(input string, returnType reflect.Type) (reflect.Value, error) {
+	if returnType == reflect.TypeFor[[]byte]() {
+		return reflect.ValueOf([]byte(input)), nil
+	}
+
+	if returnType == reflect.TypeFor[url.URL]() {
+		u, err := url.Parse(input)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("Error parsing %s: %v", returnType, err)
+		}
+		return reflect.ValueOf(*u), nil
+	}
+
+	if returnType == reflect.TypeFor[time.Duration]() {
+		d, err := time.ParseDuration(input)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("Error parsing %s: %v", returnType, err)
+		}
+		return reflect.ValueOf(d), nil
+	}
+
+	ptr := reflect.New(returnType)
+	if v, ok := ptr.Interface().(Value); ok {
+		if err := v.Set(input); err != nil {
+			return reflect.Value{}, fmt.Errorf("Error parsing %s: %v", returnType, err)
+		}
+		return ptr.Elem(), nil
+	}
+	if u, ok := ptr.Interface().(encoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText([]byte(input)); err != nil {
+			return reflect.Value{}, fmt.Errorf("Error parsing %s: %v", returnType, err)
+		}
+		return ptr.Elem(), nil
+	}
+
 	switch returnType.Kind() {
 	case reflect.Bool:
 		b, err := strconv.ParseBool(input)
 		if err != nil {
 			return reflect.Value{}, fmt.Errorf("Error parsing %s: %v", returnType, err)
 		}
-		return reflect.ValueOf(b), nil
+		return reflect.ValueOf(b).Convert(returnType), nil
 	case reflect.Float32, reflect.Float64:
 		bitSize := 64
 		if returnType.Kind() == reflect.Float32 {
@@ -34,7 +90,8 @@ func ValToType(input string, returnType reflect.Type) (reflect.Value, error) {
 		case reflect.Int32:
 			bitSize = 32
 		}
-		i, err := strconv.ParseInt(input, 10, bitSize)
+		number, base := integerBase(input)
+		i, err := strconv.ParseInt(number, base, bitSize)
 		if err != nil {
 			return reflect.Value{}, fmt.Errorf("Error parsing %s: %v", returnType, err)
 		}
@@ -49,7 +106,8 @@ func ValToType(input string, returnType reflect.Type) (reflect.Value, error) {
 		case reflect.Uint32:
 			bitSize = 32
 		}
-		u, err := strconv.ParseUint(input, 10, bitSize)
+		number, base := integerBase(input)
+		u, err := strconv.ParseUint(number, base, bitSize)
 		if err != nil {
 			return reflect.Value{}, fmt.Errorf("Error parsing %s: %v", returnType, err)
 		}
@@ -65,7 +123,23 @@ func ValToType(input string, returnType reflect.Type) (reflect.Value, error) {
 		}
 		return reflect.ValueOf(c).Convert(returnType), nil
 	case reflect.String:
-		return reflect.ValueOf(input), nil
+		return reflect.ValueOf(input).Convert(returnType), nil
 	}
 	return reflect.Value{}, fmt.Errorf("Type `%s` is unsupported, please use a supported type.", returnType)
 }
+
+// Returns the integer and the base to parse it with. Integers with a `0x`,
+// `0o` or `0b` prefix are parsed with base 0, so the prefix sets the base.
+// Other integers are decimal, even with leading zeros, and have their
+// underscores removed.
+func integerBase(input string) (string, int) {
+	digits := strings.TrimLeft(input, "+-")
+	if len(digits) > 1 && digits[0] == '0' && strings.ContainsRune("xXoObB", rune(digits[1])) {
+		return input, 0
+	}
+	if slices.Contains(strings.Split(digits, "_"), "") {
+		// underscores must be between digits
+		return input, 10
+	}
+	return strings.ReplaceAll(input, "_", ""), 10
+}
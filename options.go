@@ -0,0 +1,90 @@
+package applause
+
+import (
+	"io"
+	"strings"
+
+	"github.com/noclaps/applause/internal/parser"
+)
+
+// An option that changes how [Parse] behaves.
+type Option func(*parser.Settings)
+
+// Enables loading option values from a config file. The path of the config
+// file can be set with the built-in `--config <path>` option, and if it
+// isn't, the file at the given path is loaded if it exists. If the path is
+// empty, the default is `config.json` in the program's directory inside the
+// user config directory, for instance `$XDG_CONFIG_HOME/program/config.json`.
+//
+// The keys in the config file are the option names, and the options for
+// commands are in nested tables named after the command. Values are taken
+// from the command line first, then from environment variables set with the
+// `env` tag, then from the config file, and then from the struct.
+//
+// JSON config files are supported by default, and other formats can be
+// supported with [ConfigDecoder].
+func ConfigFile(path string) Option {
+	return func(s *parser.Settings) {
+		s.ConfigEnabled = true
+		s.ConfigFile = path
+	}
+}
+
+// Sets the function used to decode config files with the given extension,
+// for instance `ConfigDecoder(".toml", toml.Unmarshal)`. The function
+// should decode the data into the map pointed to by v.
+func ConfigDecoder(ext string, decode func(data []byte, v any) error) Option {
+	return func(s *parser.Settings) {
+		if s.ConfigDecoders == nil {
+			s.ConfigDecoders = make(map[string]func([]byte, any) error)
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		s.ConfigDecoders[strings.ToLower(ext)] = decode
+	}
+}
+
+// Enables response files, so an argument like `@args.txt` is replaced with
+// the arguments read from `args.txt`. Arguments in the file are separated by
+// whitespace, and can be quoted with single or double quotes or escaped with
+// a backslash, like in a shell. Words starting with `#` are comments that
+// last until the end of the line. Response files can include other response
+// files, and arguments after `--` are not expanded, even if the `--` is in
+// a response file.
+func ResponseFiles() Option {
+	return func(s *parser.Settings) {
+		s.ResponseFiles = true
+	}
+}
+
+// Sets where warnings are written, for instance when a deprecated argument,
+// option or command is used. The default is stderr.
+func WarningWriter(w io.Writer) Option {
+	return func(s *parser.Settings) {
+		s.Warnings = w
+	}
+}
+
+// Only allows command names as the first argument of a command that has
+// subcommands, so an argument that isn't the name of a command returns an
+// error instead of being parsed as one of the command's arguments.
+func StrictCommands() Option {
+	return func(s *parser.Settings) {
+		s.StrictCommands = true
+	}
+}
+
+// Enables external commands, so running the program with a command that
+// doesn't exist, like `program foo`, runs the executable named
+// `program-foo` on `PATH` if there is one. The remaining arguments are
+// passed to the executable along with the environment, and [Parse]
+// returns an [*ExternalCommandError] with its exit code, which wraps
+// [ErrExternalCommand] even if it succeeded. External commands
+// found on `PATH` are listed in the help text when it's printed, and offered
+// in completions.
+func ExternalCommands() Option {
+	return func(s *parser.Settings) {
+		s.ExternalCommands = true
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/noclaps/applause"
+)
+
+type Args struct {
+	List *List `type:"command" default:"true" help:"List installed packages"`
+	Add  *Add  `type:"command" help:"Install packages"`
+
+	Verbose bool   `type:"option" short:"v" persistent:"true" help:"Print what's being done"`
+	Prefix  string `type:"option" persistent:"true" help:"Where packages are installed"`
+}
+
+func (a *Args) BeforeParse() {
+	a.Prefix = "/usr/local"
+}
+
+func (a *Args) PreRun(ctx context.Context) error {
+	if a.Verbose {
+		log.Printf("Using prefix %s", a.Prefix)
+	}
+	return nil
+}
+
+func (a *Args) PostRun(ctx context.Context) error {
+	if a.Verbose {
+		log.Println("Done")
+	}
+	return nil
+}
+
+type List struct {
+	All bool `type:"option" short:"a" help:"Include dependencies"`
+}
+
+func (l *List) Run(ctx context.Context) error {
+	args := applause.Parent[Args](ctx)
+	log.Printf("Listing packages in %s (all: %t)", args.Prefix, l.All)
+	return nil
+}
+
+type Add struct {
+	Packages []string `help:"Packages to install"`
+	Force    bool     `type:"option" short:"f" help:"Reinstall packages"`
+}
+
+func (a *Add) AfterParse() error {
+	if len(a.Packages) == 0 {
+		return errors.New("No packages given")
+	}
+	return nil
+}
+
+func (a *Add) Run(ctx context.Context) error {
+	args := applause.Parent[Args](ctx)
+	for _, pkg := range a.Packages {
+		log.Printf("Installing %s to %s (force: %t)", pkg, args.Prefix, a.Force)
+	}
+	return nil
+}
+
+func main() {
+	args := Args{}
+	if err := applause.Execute(context.Background(), &args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(applause.ExitCode(err))
+	}
+}
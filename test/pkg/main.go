@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"strings"
 
 	"github.com/noclaps/applause"
 )
@@ -29,14 +30,12 @@ func main() {
 		log.Fatalln(err)
 	}
 
-	if len(args.Add.Packages) > 0 {
+	switch strings.Join(applause.CommandPath(), " ") {
+	case "add":
 		log.Println(args.Add.Packages)
-		return
-	}
-	if args.Update != nil {
+	case "update":
 		log.Println(args.Update.Packages)
-		return
+	default:
+		log.Println(args)
 	}
-
-	log.Println(args)
 }